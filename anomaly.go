@@ -0,0 +1,576 @@
+package anydiff
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/unixpickle/anyvec"
+)
+
+var anomalyDetection int32
+
+// SetAnomalyDetection enables or disables anomaly
+// detection for results created by Watch.
+//
+// Results which are watched while anomaly detection is
+// disabled will never be checked.
+//
+// While anomaly detection is enabled, the construction
+// site of every new graph node is recorded so that
+// anomalies can be traced back to the code that created
+// the offending op.
+func SetAnomalyDetection(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&anomalyDetection, 1)
+	} else {
+		atomic.StoreInt32(&anomalyDetection, 0)
+	}
+}
+
+// AnomalyDetection returns whether or not anomaly
+// detection is enabled.
+func AnomalyDetection() bool {
+	return atomic.LoadInt32(&anomalyDetection) != 0
+}
+
+// An Anomaly is a NaN or Inf value found by a watched
+// result.
+// Watched results panic with an *Anomaly.
+type Anomaly struct {
+	// Op is the name of the first operation found to
+	// produce a bad value.
+	//
+	// For a forward anomaly, this is the first node in the
+	// watched graph (with inputs before outputs) to have a
+	// bad output.
+	// For a backward anomaly, this is the node whose
+	// Propagate method passed a bad upstream vector to one
+	// of its inputs.
+	// If the upstream vector passed to the watched result
+	// itself is bad, this is the watched result's op.
+	Op string
+
+	// Node is the graph node named by Op.
+	Node interface{}
+
+	// Site is the source location where Node was created,
+	// as recorded by RecordAnomalySite.
+	// It is empty if Node was created while anomaly
+	// detection was disabled.
+	Site string
+
+	// WatchSite is the source location where Watch was
+	// called.
+	WatchSite string
+
+	// Backward is true if the anomaly was found in an
+	// upstream vector during back-propagation, and false if
+	// it was found in an output.
+	Backward bool
+}
+
+// Error returns a description of the anomaly.
+func (a *Anomaly) Error() string {
+	desc := "non-finite output of " + a.Op
+	if a.Backward {
+		desc = "non-finite upstream gradient from " + a.Op
+	}
+	if a.Site != "" {
+		desc += " created at " + a.Site
+	}
+	return fmt.Sprintf("%s (watched at %s)", desc, a.WatchSite)
+}
+
+// AnomalySite returns the source location of the caller
+// skip frames above the caller of AnomalySite.
+//
+// This is useful for implementing Watch for other kinds
+// of results.
+func AnomalySite(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+var anomalySites = struct {
+	sync.Mutex
+	m map[uintptr]string
+}{m: map[uintptr]string{}}
+
+// RecordAnomalySite records the source location where a
+// graph node is being created, if anomaly detection is
+// enabled.
+// It should be called by every function which creates a
+// new kind of graph node.
+//
+// The recorded location is the first caller outside of
+// the package that calls RecordAnomalySite and outside of
+// this package, so it usually points at user code rather
+// than at a helper that built the node.
+//
+// The node must be a pointer.
+// Its location is forgotten once it is garbage collected.
+func RecordAnomalySite(node interface{}) {
+	if !AnomalyDetection() {
+		return
+	}
+	val := reflect.ValueOf(node)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return
+	}
+	key := val.Pointer()
+	anomalySites.Lock()
+	defer anomalySites.Unlock()
+	if _, ok := anomalySites.m[key]; ok {
+		return
+	}
+	anomalySites.m[key] = externalCallSite()
+	runtime.SetFinalizer(node, func(interface{}) {
+		anomalySites.Lock()
+		delete(anomalySites.m, key)
+		anomalySites.Unlock()
+	})
+}
+
+// NodeSite returns the location recorded for a node by
+// RecordAnomalySite, or "" if no location was recorded.
+func NodeSite(node interface{}) string {
+	val := reflect.ValueOf(node)
+	if val.Kind() != reflect.Ptr {
+		return ""
+	}
+	anomalySites.Lock()
+	defer anomalySites.Unlock()
+	return anomalySites.m[val.Pointer()]
+}
+
+// externalCallSite finds the first caller of
+// RecordAnomalySite outside of the library packages.
+func externalCallSite() string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	libPkgs := map[string]bool{reflect.TypeOf(Var{}).PkgPath(): true}
+	first := true
+	for {
+		frame, more := frames.Next()
+		pkg := funcPackage(frame.Function)
+		if first {
+			libPkgs[pkg] = true
+			first = false
+		}
+		if !libPkgs[pkg] {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// funcPackage gets the package path from a fully
+// qualified function name, such as
+// "github.com/foo/bar.(*Baz).Method.func1".
+func funcPackage(name string) string {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+// trackRes records the construction site of r and
+// returns r.
+func trackRes(r Res) Res {
+	RecordAnomalySite(r)
+	return r
+}
+
+// trackMultiRes is like trackRes for a MultiRes.
+func trackMultiRes(m MultiRes) MultiRes {
+	RecordAnomalySite(m)
+	return m
+}
+
+// AnomalyOp returns the name of the operation which
+// produced a node, as used for Anomaly.Op.
+func AnomalyOp(node interface{}) string {
+	if gn, ok := node.(GraphNode); ok {
+		return gn.NodeInfo().Op
+	}
+	return fmt.Sprintf("%T", node)
+}
+
+// NonFinite returns true if any component of v is NaN or
+// Inf.
+func NonFinite(v anyvec.Vector) bool {
+	for _, x := range v.Creator().Float64Slice(v.Data()) {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+type watchRes struct {
+	In   Res
+	Site string
+
+	instrumented Res
+}
+
+// Watch checks r for NaN and Inf values when anomaly
+// detection is enabled.
+//
+// The output of every node in r's graph is checked
+// immediately, and every upstream vector passed between
+// nodes in r's graph is checked during back-propagation.
+// If a bad value is found, Watch or Propagate panics with
+// an *Anomaly naming the first operation to produce a bad
+// value, along with the location where that operation was
+// created (if it was created while anomaly detection was
+// enabled).
+//
+// Back-propagation goes through a shallow copy of the
+// graph in which every input is wrapped with a check, so
+// the graph itself is not modified.
+// Inputs are only checked if they are stored in exported
+// struct fields (or slices) of type Res, MultiRes, or a
+// type registered with RegisterAnomalyKind.
+//
+// If anomaly detection is disabled, r is returned as-is.
+func Watch(r Res) Res {
+	if !AnomalyDetection() {
+		return r
+	}
+	site := AnomalySite(1)
+	CheckAnomalies(r, site)
+	return &watchRes{In: r, Site: site}
+}
+
+func (w *watchRes) Output() anyvec.Vector {
+	return w.In.Output()
+}
+
+func (w *watchRes) Vars() VarSet {
+	return w.In.Vars()
+}
+
+func (w *watchRes) Propagate(u anyvec.Vector, g Grad) {
+	if NonFinite(u) {
+		panic(&Anomaly{
+			Op:        AnomalyOp(w.In),
+			Node:      w.In,
+			Site:      NodeSite(w.In),
+			WatchSite: w.Site,
+			Backward:  true,
+		})
+	}
+	if w.instrumented == nil {
+		w.instrumented = InstrumentAnomalies(w.In, w.Site).(Res)
+	}
+	w.instrumented.Propagate(u, g)
+}
+
+func (w *watchRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Watch",
+		Inputs:    []interface{}{w.In},
+		OutputLen: w.In.Output().Len(),
+	}
+}
+
+// An AnomalyKind tells anomaly detection how to handle a
+// kind of graph node other than Res and MultiRes, such as
+// anyseq.Seq.
+type AnomalyKind struct {
+	// Type is the interface type implemented by nodes of
+	// this kind.
+	Type reflect.Type
+
+	// NonFinite checks if a node's output contains any NaN
+	// or Inf values.
+	NonFinite func(node interface{}) bool
+
+	// Wrap wraps a node so that report is called if the
+	// node's Propagate method is called with a non-finite
+	// upstream value.
+	// The result must implement Type.
+	Wrap func(node interface{}, report func()) interface{}
+}
+
+var anomalyKinds []*AnomalyKind
+
+// RegisterAnomalyKind adds support for a kind of graph
+// node to anomaly detection.
+//
+// This should be called during package initialization.
+func RegisterAnomalyKind(k *AnomalyKind) {
+	anomalyKinds = append(anomalyKinds, k)
+}
+
+// CheckAnomalies panics with an *Anomaly if any node in
+// the graph under root has a non-finite output.
+// The watchSite is used for Anomaly.WatchSite.
+//
+// This is useful for implementing Watch for other kinds
+// of graph nodes.
+func CheckAnomalies(root interface{}, watchSite string) {
+	var bad interface{}
+	visitAnomalyGraph(root, func(node interface{}) bool {
+		if anomalyNonFinite(node) {
+			bad = node
+			return false
+		}
+		return true
+	})
+	if bad != nil {
+		panic(&Anomaly{
+			Op:        AnomalyOp(bad),
+			Node:      bad,
+			Site:      NodeSite(bad),
+			WatchSite: watchSite,
+		})
+	}
+}
+
+// InstrumentAnomalies creates a shallow copy of the graph
+// under root in which every input is wrapped so that
+// back-propagating a non-finite vector into it panics
+// with an *Anomaly.
+// The watchSite is used for Anomaly.WatchSite.
+//
+// This is useful for implementing Watch for other kinds
+// of graph nodes.
+func InstrumentAnomalies(root interface{}, watchSite string) interface{} {
+	inst := &anomalyInstrumenter{site: watchSite, copies: map[interface{}]interface{}{}}
+	return inst.copyNode(root)
+}
+
+// visitAnomalyGraph calls f for every node reachable from
+// root, with inputs before outputs, until f returns false.
+func visitAnomalyGraph(root interface{}, f func(node interface{}) bool) {
+	visited := map[interface{}]bool{}
+	done := false
+	var visit func(node interface{})
+	visit = func(node interface{}) {
+		if done {
+			return
+		}
+		comparable := reflect.TypeOf(node).Comparable()
+		if comparable {
+			if visited[node] {
+				return
+			}
+			visited[node] = true
+		}
+		if gn, ok := node.(GraphNode); ok {
+			for _, in := range gn.NodeInfo().Inputs {
+				visit(in)
+			}
+		}
+		if !done && !f(node) {
+			done = true
+		}
+	}
+	visit(root)
+}
+
+func anomalyNonFinite(node interface{}) bool {
+	switch node := node.(type) {
+	case Res:
+		return NonFinite(node.Output())
+	case MultiRes:
+		for _, v := range node.Outputs() {
+			if NonFinite(v) {
+				return true
+			}
+		}
+		return false
+	}
+	if k := anomalyKindOf(node); k != nil {
+		return k.NonFinite(node)
+	}
+	return false
+}
+
+func anomalyKindOf(node interface{}) *AnomalyKind {
+	for _, k := range anomalyKinds {
+		if reflect.TypeOf(node).Implements(k.Type) {
+			return k
+		}
+	}
+	return nil
+}
+
+type anomalyInstrumenter struct {
+	site   string
+	copies map[interface{}]interface{}
+}
+
+// copyNode creates an instrumented copy of a node.
+//
+// Variables and constants are never copied, since they
+// are used as keys in a Grad.
+func (a *anomalyInstrumenter) copyNode(node interface{}) interface{} {
+	switch node := node.(type) {
+	case *Var, *Const:
+		return node
+	case *watchRes:
+		return a.copyNode(node.In)
+	}
+	val := reflect.ValueOf(node)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return node
+	}
+	if res, ok := a.copies[node]; ok {
+		return res
+	}
+	cp := reflect.New(val.Elem().Type())
+	cp.Elem().Set(val.Elem())
+	res := cp.Interface()
+	a.copies[node] = res
+	a.wrapFields(cp.Elem(), node)
+	return res
+}
+
+// wrapFields wraps the inputs stored in a struct's fields.
+func (a *anomalyInstrumenter) wrapFields(s reflect.Value, parent interface{}) {
+	for i := 0; i < s.NumField(); i++ {
+		field := s.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		if wrapped, ok := a.wrapValue(field, parent); ok {
+			field.Set(wrapped)
+		}
+	}
+}
+
+func (a *anomalyInstrumenter) wrapValue(v reflect.Value, parent interface{}) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		wrapped := a.wrapNode(v.Elem().Interface(), parent)
+		if wrapped == nil || !reflect.TypeOf(wrapped).AssignableTo(v.Type()) {
+			return v, false
+		}
+		return reflect.ValueOf(wrapped), true
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() != reflect.Interface {
+			return v, false
+		}
+		res := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		var changed bool
+		for i := 0; i < v.Len(); i++ {
+			elem, ok := a.wrapValue(v.Index(i), parent)
+			changed = changed || ok
+			res.Index(i).Set(elem)
+		}
+		return res, changed
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, false
+		}
+		switch v.Interface().(type) {
+		case *Matrix, *MatrixBatch:
+			cp := reflect.New(v.Elem().Type())
+			cp.Elem().Set(v.Elem())
+			a.wrapFields(cp.Elem(), parent)
+			return cp, true
+		}
+	}
+	return v, false
+}
+
+// wrapNode wraps an instrumented copy of an input to the
+// parent node.
+// It returns nil if the input is not a graph node.
+func (a *anomalyInstrumenter) wrapNode(node, parent interface{}) interface{} {
+	report := func() {
+		panic(&Anomaly{
+			Op:        AnomalyOp(parent),
+			Node:      parent,
+			Site:      NodeSite(parent),
+			WatchSite: a.site,
+			Backward:  true,
+		})
+	}
+	switch node.(type) {
+	case *Const:
+		return nil
+	case Res:
+		return &anomalyRes{In: a.copyNode(node).(Res), Report: report}
+	case MultiRes:
+		return &anomalyMultiRes{In: a.copyNode(node).(MultiRes), Report: report}
+	}
+	if k := anomalyKindOf(node); k != nil {
+		return k.Wrap(a.copyNode(node), report)
+	}
+	return nil
+}
+
+type anomalyRes struct {
+	In     Res
+	Report func()
+}
+
+func (a *anomalyRes) Output() anyvec.Vector {
+	return a.In.Output()
+}
+
+func (a *anomalyRes) Vars() VarSet {
+	return a.In.Vars()
+}
+
+func (a *anomalyRes) Propagate(u anyvec.Vector, g Grad) {
+	if NonFinite(u) {
+		a.Report()
+	}
+	a.In.Propagate(u, g)
+}
+
+func (a *anomalyRes) NodeInfo() *NodeInfo {
+	if gn, ok := a.In.(GraphNode); ok {
+		return gn.NodeInfo()
+	}
+	return &NodeInfo{Op: AnomalyOp(a.In), OutputLen: a.In.Output().Len()}
+}
+
+type anomalyMultiRes struct {
+	In     MultiRes
+	Report func()
+}
+
+func (a *anomalyMultiRes) Outputs() []anyvec.Vector {
+	return a.In.Outputs()
+}
+
+func (a *anomalyMultiRes) Vars() VarSet {
+	return a.In.Vars()
+}
+
+func (a *anomalyMultiRes) Propagate(u []anyvec.Vector, g Grad) {
+	for _, v := range u {
+		if v != nil && NonFinite(v) {
+			a.Report()
+		}
+	}
+	a.In.Propagate(u, g)
+}
+
+func (a *anomalyMultiRes) NodeInfo() *NodeInfo {
+	if gn, ok := a.In.(GraphNode); ok {
+		return gn.NodeInfo()
+	}
+	return &NodeInfo{Op: AnomalyOp(a.In), OutputLen: multiOutputLen(a.In.Outputs())}
+}
@@ -1,6 +1,7 @@
 package anydifftest
 
 import (
+	"math"
 	"testing"
 
 	"github.com/unixpickle/anydiff"
@@ -65,3 +66,165 @@ func TestSeqSumEachProp(t *testing.T) {
 		ch.FullCheck(t)
 	})
 }
+
+func TestSeqMeanEachOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		actual := getComponents(anyseq.MeanEach(aggregateTestSeqs(c)).Output())
+		expected := []float64{1.0 / 3, 2.0 / 3, 0.5, 3.5, 9, 1, -2, 0.5}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestSeqMeanEachProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anyseq.MeanEach(inSeq)
+			},
+			V: varList,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestSeqMaxEachOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		actual := getComponents(anyseq.MaxEach(aggregateTestSeqs(c)).Output())
+		expected := []float64{2, 2, 1, 5, 9, 1, 2, 2}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestSeqMaxEachProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anyseq.MaxEach(inSeq)
+			},
+			V: varList,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestSeqLogSumExpEachOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq := aggregateTestSeqs(c)
+		actual := getComponents(anyseq.LogSumExpEach(inSeq).Output())
+		var expected []float64
+		for _, seq := range anyseq.SeparateSeqs(inSeq.Output()) {
+			if len(seq) == 0 {
+				continue
+			}
+			sums := make([]float64, 2)
+			for _, x := range seq {
+				for i, comp := range getComponents(x) {
+					sums[i] += math.Exp(comp)
+				}
+			}
+			expected = append(expected, math.Log(sums[0]), math.Log(sums[1]))
+		}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestSeqLogSumExpEachProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anyseq.LogSumExpEach(inSeq)
+			},
+			V: varList,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestSeqLogSumExpEachWatch(t *testing.T) {
+	anydiff.SetAnomalyDetection(true)
+	defer anydiff.SetAnomalyDetection(false)
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		watched := anydiff.Watch(anyseq.LogSumExpEach(inSeq))
+		upstream := c.MakeVector(watched.Output().Len())
+		upstream.AddScalar(c.MakeNumeric(1))
+		watched.Propagate(upstream, anydiff.NewGrad(varList...))
+	})
+}
+
+func TestSeqAttentionEachOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq := aggregateTestSeqs(c)
+		query := anydiff.NewConst(makeVecData(c, 1, -0.5))
+		actual := getComponents(anyseq.AttentionEach(inSeq, query).Output())
+		var expected []float64
+		for _, seq := range anyseq.SeparateSeqs(inSeq.Output()) {
+			if len(seq) == 0 {
+				continue
+			}
+			var weightSum float64
+			sums := make([]float64, 2)
+			for _, x := range seq {
+				comps := getComponents(x)
+				weight := math.Exp((comps[0] - 0.5*comps[1]) / math.Sqrt(2))
+				weightSum += weight
+				for i, comp := range comps {
+					sums[i] += weight * comp
+				}
+			}
+			expected = append(expected, sums[0]/weightSum, sums[1]/weightSum)
+		}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestSeqAttentionEachProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		query := makeRandomVec(c, 6)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anyseq.AttentionEach(inSeq, query)
+			},
+			V:    append(varList, query),
+			Prec: prec * 4,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func aggregateTestSeqs(c anyvec.Creator) anyseq.Seq {
+	return anyseq.ConstSeqList(c, [][]anyvec.Vector{
+		{
+			makeVecData(c, 1, 2),
+			makeVecData(c, -2, 1),
+			makeVecData(c, 2, -1),
+		},
+		{},
+		{
+			makeVecData(c, 1, 2),
+			makeVecData(c, 0, 5),
+		},
+		{
+			makeVecData(c, 9, 1),
+		},
+		{},
+		{
+			makeVecData(c, 1, 2),
+			makeVecData(c, -2, 1),
+			makeVecData(c, 2, -1),
+			makeVecData(c, -9, 0),
+		},
+	})
+}
@@ -0,0 +1,215 @@
+package anydifftest
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestWatchDisabled(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := makeRandomVec(c, 5)
+		r := anydiff.Tanh(v)
+		if anydiff.Watch(r) != r {
+			t.Error("Watch should be a no-op when disabled")
+		}
+		s, _ := makeBasicTestSeqs(c)
+		if anyseq.Watch(s) != s {
+			t.Error("anyseq.Watch should be a no-op when disabled")
+		}
+	})
+}
+
+func TestWatchForward(t *testing.T) {
+	anydiff.SetAnomalyDetection(true)
+	defer anydiff.SetAnomalyDetection(false)
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := anydiff.NewVar(c.MakeVectorData(c.MakeNumericList([]float64{1, 0, 2})))
+		anydiff.Watch(anydiff.Tanh(v))
+		a := expectAnomaly(t, func() {
+			anydiff.Watch(anydiff.Div(v, v))
+		})
+		if a != nil && (a.Op != "Div" || a.Backward) {
+			t.Errorf("unexpected anomaly: %v", a)
+		}
+	})
+}
+
+func TestWatchBackward(t *testing.T) {
+	anydiff.SetAnomalyDetection(true)
+	defer anydiff.SetAnomalyDetection(false)
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := anydiff.NewVar(c.MakeVectorData(c.MakeNumericList([]float64{1, 0, 2})))
+		r := anydiff.Watch(anydiff.Sin(v))
+		out := anydiff.Pow(r, c.MakeNumeric(0.5))
+		a := expectAnomaly(t, func() {
+			up := c.MakeVectorData(c.MakeNumericList([]float64{1, math.Inf(1), 1}))
+			out.Propagate(up, anydiff.NewGrad(v))
+		})
+		if a != nil && (a.Op != "Sin" || !a.Backward) {
+			t.Errorf("unexpected anomaly: %v", a)
+		}
+	})
+}
+
+func TestWatchSeq(t *testing.T) {
+	anydiff.SetAnomalyDetection(true)
+	defer anydiff.SetAnomalyDetection(false)
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		s, vars := makeBasicTestSeqs(c)
+		watched := anyseq.Watch(s)
+		expectAnomaly(t, func() {
+			var upstream []*anyseq.Batch
+			for _, b := range watched.Output() {
+				vec := c.MakeVector(b.Packed.Len())
+				vec.AddScalar(c.MakeNumeric(math.NaN()))
+				upstream = append(upstream, &anyseq.Batch{
+					Packed:  vec,
+					Present: b.Present,
+				})
+			}
+			watched.Propagate(upstream, anydiff.NewGrad(vars...))
+		})
+		vars[0].Vector.Scale(c.MakeNumeric(math.Inf(1)))
+		expectAnomaly(t, func() {
+			anyseq.Watch(s)
+		})
+	})
+}
+
+func TestWatchForwardFirstOp(t *testing.T) {
+	anydiff.SetAnomalyDetection(true)
+	defer anydiff.SetAnomalyDetection(false)
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := anydiff.NewVar(c.MakeVectorData(c.MakeNumericList([]float64{1, 0, 2})))
+		var line int
+		a := expectAnomaly(t, func() {
+			line = currentLine() + 1
+			anydiff.Watch(anydiff.Tanh(anydiff.Exp(anydiff.Div(v, v))))
+		})
+		if a != nil && (a.Op != "Div" || a.Backward) {
+			t.Errorf("unexpected anomaly: %v", a)
+		}
+		checkAnomalySite(t, a, line)
+	})
+}
+
+func TestWatchBackwardFirstOp(t *testing.T) {
+	anydiff.SetAnomalyDetection(true)
+	defer anydiff.SetAnomalyDetection(false)
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := anydiff.NewVar(c.MakeVectorData(c.MakeNumericList([]float64{1, 0, 2})))
+		line := currentLine() + 1
+		r := anydiff.Tanh(anydiff.Pow(v, c.MakeNumeric(0.5)))
+		out := anydiff.Watch(r)
+		a := expectAnomaly(t, func() {
+			up := c.MakeVectorData(c.MakeNumericList([]float64{1, 1, 1}))
+			out.Propagate(up, anydiff.NewGrad(v))
+		})
+		if a != nil && (a.Op != "Pow" || !a.Backward) {
+			t.Errorf("unexpected anomaly: %v", a)
+		}
+		checkAnomalySite(t, a, line)
+
+		// The original graph should not be instrumented.
+		g := anydiff.NewGrad(v)
+		r.Propagate(c.MakeVectorData(c.MakeNumericList([]float64{1, 1, 1})), g)
+
+		// The instrumented graph should describe itself like
+		// the original graph.
+		var expected, actual bytes.Buffer
+		anydiff.WriteDOT(&expected, r)
+		anydiff.WriteDOT(&actual, anydiff.InstrumentAnomalies(r, ""))
+		if actual.String() != expected.String() {
+			t.Errorf("expected graph %s but got %s", expected.String(), actual.String())
+		}
+	})
+}
+
+func TestWatchSeqFirstOp(t *testing.T) {
+	anydiff.SetAnomalyDetection(true)
+	defer anydiff.SetAnomalyDetection(false)
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		s, vars := makeBasicTestSeqs(c)
+		vars[0].Vector.Scale(c.MakeNumeric(0))
+		line := currentLine() + 2
+		mapped := anyseq.Map(s, func(v anydiff.Res, n int) anydiff.Res {
+			return anydiff.Pow(anydiff.Square(v), c.MakeNumeric(0.5))
+		})
+
+		watchedSeq := anyseq.Watch(mapped)
+		a := expectAnomaly(t, func() {
+			var upstream []*anyseq.Batch
+			for _, b := range watchedSeq.Output() {
+				vec := c.MakeVector(b.Packed.Len())
+				vec.AddScalar(c.MakeNumeric(1))
+				upstream = append(upstream, &anyseq.Batch{
+					Packed:  vec,
+					Present: b.Present,
+				})
+			}
+			watchedSeq.Propagate(upstream, anydiff.NewGrad(vars...))
+		})
+		if a != nil && (a.Op != "Pow" || !a.Backward) {
+			t.Errorf("unexpected anomaly: %v", a)
+		}
+		checkAnomalySite(t, a, line)
+
+		watched := anydiff.Watch(anyseq.SumEach(mapped))
+		a = expectAnomaly(t, func() {
+			up := c.MakeVector(watched.Output().Len())
+			up.AddScalar(c.MakeNumeric(1))
+			watched.Propagate(up, anydiff.NewGrad(vars...))
+		})
+		if a != nil && (a.Op != "Pow" || !a.Backward) {
+			t.Errorf("unexpected anomaly: %v", a)
+		}
+	})
+}
+
+func expectAnomaly(t *testing.T, f func()) (res *anydiff.Anomaly) {
+	defer func() {
+		err := recover()
+		if err == nil {
+			t.Error("expected anomaly")
+			return
+		}
+		a, ok := err.(*anydiff.Anomaly)
+		if !ok {
+			panic(err)
+		}
+		if a.WatchSite == "" || a.WatchSite == "unknown" {
+			t.Errorf("bad watch site: %s", a.WatchSite)
+		}
+		res = a
+	}()
+	f()
+	return nil
+}
+
+// checkAnomalySite checks that the anomaly's node was
+// created on the given line of this file.
+func checkAnomalySite(t *testing.T, a *anydiff.Anomaly, line int) {
+	if a == nil {
+		return
+	}
+	expected := fmt.Sprintf("anomaly_test.go:%d", line)
+	if !strings.HasSuffix(a.Site, expected) {
+		t.Errorf("expected site %s but got %s", expected, a.Site)
+	}
+	if !strings.Contains(a.Error(), "created at "+a.Site) {
+		t.Errorf("error message missing site: %s", a.Error())
+	}
+}
+
+func currentLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
@@ -0,0 +1,183 @@
+package anydifftest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestAttentionOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		q := &anydiff.MatrixBatch{
+			Data: anydiff.NewConst(makeVecData(c, 1, 0, 0, 2)),
+			Num:  1,
+			Rows: 2,
+			Cols: 2,
+		}
+		k := &anydiff.MatrixBatch{
+			Data: anydiff.NewConst(makeVecData(c, 1, 1, 0, 1, 3, -1)),
+			Num:  1,
+			Rows: 3,
+			Cols: 2,
+		}
+		v := &anydiff.MatrixBatch{
+			Data: anydiff.NewConst(makeVecData(c, 1, 2, 3)),
+			Num:  1,
+			Rows: 3,
+			Cols: 1,
+		}
+		mask := anydiff.NewConst(makeVecData(c, 0, 0, -1e9, 0, 0, -1e9))
+		actual := getComponents(anydiff.Attention(q, k, v, mask).Data.Output())
+
+		scale := 1 / math.Sqrt(2)
+		expected := make([]float64, 2)
+		for i, scores := range [][]float64{{1, 0}, {2, 2}} {
+			w1 := math.Exp(scores[0] * scale)
+			w2 := math.Exp(scores[1] * scale)
+			expected[i] = (w1*1 + w2*2) / (w1 + w2)
+		}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestAttentionProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		q := makeRandomVec(c, 2*3*4)
+		k := makeRandomVec(c, 2*5*4)
+		v := makeRandomVec(c, 2*5*3)
+		mask := makeRandomVec(c, 2*3*5)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anydiff.Attention(
+					&anydiff.MatrixBatch{Data: q, Num: 2, Rows: 3, Cols: 4},
+					&anydiff.MatrixBatch{Data: k, Num: 2, Rows: 5, Cols: 4},
+					&anydiff.MatrixBatch{Data: v, Num: 2, Rows: 5, Cols: 3},
+					mask,
+				).Data
+			},
+			V:    []*anydiff.Var{q, k, v, mask},
+			Prec: prec * 4,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestMultiHeadAttentionOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, _ := makeBasicTestSeqs(c)
+		block := anyseq.NewMultiHeadAttention(c, 2, 6, 4)
+
+		// Attention should not leak between sequences or
+		// pick up padding timesteps.
+		actual := anyseq.SeparateSeqs(block.Apply(inSeq).Output())
+		for i, seq := range anyseq.SeparateSeqs(inSeq.Output()) {
+			single := anyseq.ConstSeqList(c, [][]anyvec.Vector{seq})
+			expected := block.Apply(single)
+			if !SeqsClose(anyseq.ConstSeqList(c, actual[i:i+1]), expected, prec) {
+				t.Errorf("unexpected output for sequence %d", i)
+			}
+		}
+	})
+}
+
+func TestMultiHeadAttentionProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		block := anyseq.NewMultiHeadAttention(c, 2, 6, 4)
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return block.Apply(inSeq)
+			},
+			V:    append(block.Parameters(), varList...),
+			Prec: prec * 4,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestCrossAttention(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		memBatches := []*anyseq.ResBatch{
+			{
+				Packed:  makeRandomVec(c, 9),
+				Present: []bool{true, true, false, true},
+			},
+			{
+				Packed:  makeRandomVec(c, 6),
+				Present: []bool{false, true, false, true},
+			},
+			{
+				Packed:  makeRandomVec(c, 3),
+				Present: []bool{false, true, false, false},
+			},
+		}
+		memory := anyseq.ResSeq(c, memBatches)
+		for _, b := range memBatches {
+			for v := range b.Packed.Vars() {
+				varList = append(varList, v)
+			}
+		}
+		block := anyseq.NewCrossAttention(c, 2, 6, 3, 4)
+
+		actual := anyseq.SeparateSeqs(block.ApplyCross(inSeq, memory).Output())
+		inSeqs := anyseq.SeparateSeqs(inSeq.Output())
+		for i, seq := range anyseq.SeparateSeqs(memory.Output()) {
+			if len(seq) == 0 {
+				continue
+			}
+			expected := block.ApplyCross(
+				anyseq.ConstSeqList(c, inSeqs[i:i+1]),
+				anyseq.ConstSeqList(c, [][]anyvec.Vector{seq}),
+			)
+			if !SeqsClose(anyseq.ConstSeqList(c, actual[i:i+1]), expected, prec) {
+				t.Errorf("unexpected output for sequence %d", i)
+			}
+		}
+
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return block.ApplyCross(inSeq, memory)
+			},
+			V:    append(block.Parameters(), varList...),
+			Prec: prec * 4,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestCrossAttentionPropagateOnce(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		memory := &countingSeq{Seq: inSeq}
+		block := anyseq.NewCrossAttention(c, 2, 6, 6, 4)
+		out := block.ApplyCross(inSeq, memory)
+
+		var upstream []*anyseq.Batch
+		for _, b := range out.Output() {
+			vec := c.MakeVector(b.Packed.Len())
+			vec.AddScalar(c.MakeNumeric(1))
+			upstream = append(upstream, &anyseq.Batch{Packed: vec, Present: b.Present})
+		}
+		out.Propagate(upstream, anydiff.NewGrad(varList...))
+		if memory.Calls != 1 {
+			t.Errorf("memory propagated %d times", memory.Calls)
+		}
+	})
+}
+
+// countingSeq counts calls to Propagate.
+type countingSeq struct {
+	anyseq.Seq
+	Calls int
+}
+
+func (c *countingSeq) Propagate(u []*anyseq.Batch, g anydiff.Grad) {
+	c.Calls++
+	c.Seq.Propagate(u, g)
+}
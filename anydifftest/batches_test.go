@@ -0,0 +1,82 @@
+package anydifftest
+
+import (
+	"testing"
+
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestMergeBatches(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		other := anyseq.Reverse(anyseq.Slice(inSeq, 1, 3))
+		actual := anyseq.MergeBatches(inSeq, other)
+		expected := append(anyseq.SeparateSeqs(inSeq.Output()),
+			anyseq.SeparateSeqs(other.Output())...)
+		if !SeqsClose(actual, anyseq.ConstSeqList(c, expected), prec) {
+			t.Error("unexpected output")
+		}
+
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.MergeBatches(anyseq.Reverse(anyseq.Slice(inSeq, 1, 3)), inSeq)
+			},
+			V: varList,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestSplitBatch(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		seqs := anyseq.SeparateSeqs(inSeq.Output())
+		actual := anyseq.SplitBatch(inSeq, 1, 0, 3)
+		expected := [][][]anyvec.Vector{seqs[:1], nil, seqs[1:]}
+		for i, x := range actual {
+			if !SeqsClose(x, anyseq.ConstSeqList(c, expected[i]), prec) {
+				t.Errorf("unexpected output for sub-batch %d", i)
+			}
+		}
+		if !SeqsClose(anyseq.MergeBatches(actual...), inSeq, prec) {
+			t.Error("merging should undo splitting")
+		}
+
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.SplitBatch(inSeq, 2, 2)[1]
+			},
+			V: varList,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestPermute(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		perm := []int{2, 0, 3, 1}
+		inverse := []int{1, 3, 0, 2}
+		actual := anyseq.Permute(inSeq, perm)
+		seqs := anyseq.SeparateSeqs(inSeq.Output())
+		var expected [][]anyvec.Vector
+		for _, i := range perm {
+			expected = append(expected, seqs[i])
+		}
+		if !SeqsClose(actual, anyseq.ConstSeqList(c, expected), prec) {
+			t.Error("unexpected output")
+		}
+		if !SeqsClose(anyseq.Permute(actual, inverse), inSeq, prec) {
+			t.Error("inverse permutation should restore the batch")
+		}
+
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.Permute(inSeq, perm)
+			},
+			V: varList,
+		}
+		ch.FullCheck(t)
+	})
+}
@@ -0,0 +1,69 @@
+package anydifftest
+
+import (
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestConcatInner(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		mapped := func() anyseq.Seq {
+			return anyseq.Map(inSeq, func(v anydiff.Res, n int) anydiff.Res {
+				m := &anydiff.Matrix{Data: v, Rows: n, Cols: 6}
+				return anydiff.Sin(anydiff.SumCols(m))
+			})
+		}
+
+		actual := anyseq.ConcatInner(inSeq, mapped())
+		var expected [][]anyvec.Vector
+		seqs1 := anyseq.SeparateSeqs(inSeq.Output())
+		seqs2 := anyseq.SeparateSeqs(mapped().Output())
+		for i, seq := range seqs1 {
+			var joined []anyvec.Vector
+			for j, x := range seq {
+				joined = append(joined, c.Concat(x, seqs2[i][j]))
+			}
+			expected = append(expected, joined)
+		}
+		if !SeqsClose(actual, anyseq.ConstSeqList(c, expected), prec) {
+			t.Error("unexpected output")
+		}
+
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.ConcatInner(mapped(), inSeq, mapped())
+			},
+			V: varList,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestBidirectional(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		if _, ok := c.MakeNumeric(3.14).(float32); ok {
+			t.Skip("need more testing precision")
+		}
+		inSeq, varList := makeBasicTestSeqs(c)
+		fwd := anyseq.NewGRU(c, 6, 2)
+		bwd := anyseq.NewGRU(c, 6, 3)
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.Bidirectional(inSeq, fwd.Apply, bwd.Apply)
+			},
+			V: append(append(fwd.Parameters(), bwd.Parameters()...), varList...),
+		}
+		ch.FullCheck(t)
+
+		out := anyseq.Bidirectional(inSeq, fwd.Apply, bwd.Apply)
+		expected := anyseq.ConcatInner(fwd.Apply(inSeq),
+			anyseq.Reverse(bwd.Apply(anyseq.Reverse(inSeq))))
+		if !SeqsClose(out, expected, prec) {
+			t.Error("unexpected output")
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package anydifftest
+
+import (
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestBroadcastOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		like := aggregateTestSeqs(c)
+		vec := anydiff.NewConst(makeVecData(c, 1, 2, 3, 4, 5, 6, 7, 8))
+		actual := anyseq.Broadcast(vec, like)
+
+		var expected [][]anyvec.Vector
+		var idx int
+		for _, seq := range anyseq.SeparateSeqs(like.Output()) {
+			var repeated []anyvec.Vector
+			for range seq {
+				repeated = append(repeated, vec.Output().Slice(idx*2, idx*2+2))
+			}
+			if len(seq) > 0 {
+				idx++
+			}
+			expected = append(expected, repeated)
+		}
+		if !SeqsClose(actual, anyseq.ConstSeqList(c, expected), prec) {
+			t.Error("unexpected output")
+		}
+	})
+}
+
+func TestBroadcastProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		like, _ := makeBasicTestSeqs(c)
+		vec := makeRandomVec(c, 8)
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.Broadcast(vec, like)
+			},
+			V: []*anydiff.Var{vec},
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestAddBroadcast(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		vec := makeRandomVec(c, 24)
+		actual := anyseq.AddBroadcast(inSeq, vec)
+
+		var expected [][]anyvec.Vector
+		for i, seq := range anyseq.SeparateSeqs(inSeq.Output()) {
+			var sums []anyvec.Vector
+			for _, x := range seq {
+				sum := x.Copy()
+				sum.Add(vec.Vector.Slice(i*6, (i+1)*6))
+				sums = append(sums, sum)
+			}
+			expected = append(expected, sums)
+		}
+		if !SeqsClose(actual, anyseq.ConstSeqList(c, expected), prec) {
+			t.Error("unexpected output")
+		}
+
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.AddBroadcast(inSeq, vec)
+			},
+			V: append(varList, vec),
+		}
+		ch.FullCheck(t)
+	})
+}
@@ -0,0 +1,71 @@
+package anydifftest
+
+import (
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestCheckpoint(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v1 := makeRandomVec(c, 12)
+		v2 := makeRandomVec(c, 12)
+		w := makeRandomVec(c, 12)
+		var calls int
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				in1 := anydiff.Tanh(v1)
+				return anydiff.Checkpoint([]anydiff.Res{in1, v2}, func(r []anydiff.Res) anydiff.Res {
+					calls++
+					prod := anydiff.Mul(anydiff.Sin(r[0]), r[1])
+					return anydiff.Add(anydiff.Mul(prod, w), r[0])
+				})
+			},
+			V: []*anydiff.Var{v1, v2, w},
+		}
+		ch.FullCheck(t)
+		if calls == 0 {
+			t.Error("function was never called")
+		}
+	})
+}
+
+func TestCheckpointRecompute(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := makeRandomVec(c, 6)
+		var calls int
+		r := anydiff.Checkpoint([]anydiff.Res{anydiff.Exp(v)}, func(r []anydiff.Res) anydiff.Res {
+			calls++
+			return anydiff.Sigmoid(r[0])
+		})
+		if calls != 1 {
+			t.Fatalf("expected 1 call but got %d", calls)
+		}
+		g := anydiff.NewGrad(v)
+		r.Propagate(c.MakeVector(6), g)
+		if calls != 2 {
+			t.Errorf("expected 2 calls but got %d", calls)
+		}
+		if len(g) != 1 {
+			t.Errorf("gradient should only contain v, but has %d entries", len(g))
+		}
+	})
+}
+
+func TestMapCheckpoint(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		scale := makeRandomVec(c, 6)
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.MapCheckpoint(inSeq, func(v anydiff.Res, n int) anydiff.Res {
+					return anydiff.Tanh(anydiff.ScaleRepeated(v, scale))
+				})
+			},
+			V: append([]*anydiff.Var{scale}, varList...),
+		}
+		ch.FullCheck(t)
+	})
+}
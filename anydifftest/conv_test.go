@@ -0,0 +1,272 @@
+package anydifftest
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestConv2DOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		conv := testConv2D()
+		in := makeRandomVec(c, 2*conv.InWidth*conv.InHeight*conv.InDepth)
+		filters := makeRandomVec(c, 3*conv.FilterWidth*conv.FilterHeight*conv.InDepth)
+		actual := getComponents(conv.Apply(in, filters).Output())
+		expected := naiveConv2D(conv, getComponents(in.Vector),
+			getComponents(filters.Vector))
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestConv2DProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		conv := testConv2D()
+		in := makeRandomVec(c, 2*conv.InWidth*conv.InHeight*conv.InDepth)
+		filters := makeRandomVec(c, 3*conv.FilterWidth*conv.FilterHeight*conv.InDepth)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return conv.Apply(in, filters)
+			},
+			V: []*anydiff.Var{in, filters},
+
+			// Convolutions are linear in each input, so a large
+			// delta is exact and avoids rounding errors.
+			Delta: 0.1,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestConv2DTranspose(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		conv := testConv2D()
+		in := makeRandomVec(c, 2*conv.InWidth*conv.InHeight*conv.InDepth)
+		out := makeRandomVec(c, 2*conv.OutWidth()*conv.OutHeight()*3)
+		filters := makeRandomVec(c, 3*conv.FilterWidth*conv.FilterHeight*conv.InDepth)
+
+		// The transpose must be the adjoint of Apply.
+		convOut := conv.Apply(in, filters).Output()
+		transOut := conv.Transpose(out, filters).Output()
+		if transOut.Len() != in.Vector.Len() {
+			t.Fatalf("expected length %d but got %d", in.Vector.Len(), transOut.Len())
+		}
+		dot1 := c.Float64(convOut.Dot(out.Vector))
+		dot2 := c.Float64(transOut.Dot(in.Vector))
+		if !valuesClose(dot1, dot2, prec) {
+			t.Errorf("adjoint mismatch: %f vs %f", dot1, dot2)
+		}
+
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return conv.Transpose(out, filters)
+			},
+			V: []*anydiff.Var{out, filters},
+
+			// See comment in TestConv2DProp.
+			Delta: 0.1,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestConv1D(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		conv := &anydiff.Conv1D{
+			InLength:     7,
+			InDepth:      2,
+			FilterLength: 3,
+			Stride:       2,
+			Pad:          1,
+		}
+		if conv.OutLength() != 4 {
+			t.Errorf("expected output length 4 but got %d", conv.OutLength())
+		}
+		in := makeRandomVec(c, 14)
+		filters := makeRandomVec(c, 12)
+		expected := naiveConv2D(&anydiff.Conv2D{
+			InWidth:      7,
+			InHeight:     1,
+			InDepth:      2,
+			FilterWidth:  3,
+			FilterHeight: 1,
+			StrideX:      2,
+			PadX:         1,
+		}, getComponents(in.Vector), getComponents(filters.Vector))
+		actual := getComponents(conv.Apply(in, filters).Output())
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return conv.Apply(in, filters)
+			},
+			V: []*anydiff.Var{in, filters},
+
+			// See comment in TestConv2DProp.
+			Delta: 0.1,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestMaxPool2D(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		pool := &anydiff.MaxPool2D{
+			InWidth:  5,
+			InHeight: 4,
+			InDepth:  2,
+			Width:    2,
+			Height:   2,
+			StrideX:  1,
+		}
+		// Use well-separated values so that finite differences
+		// never change the maximum of a window.
+		inData := make([]float64, 2*5*4*2)
+		for i, x := range rand.Perm(len(inData)) {
+			inData[i] = float64(x) / 10
+		}
+		in := anydiff.NewVar(makeVecData(c, inData...))
+		actual := getComponents(pool.Apply(in).Output())
+		expected := naivePool(getComponents(in.Vector), 5, 4, 2, 2, 2, 1, 2,
+			func(x []float64) float64 {
+				res := math.Inf(-1)
+				for _, y := range x {
+					res = math.Max(res, y)
+				}
+				return res
+			})
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return pool.Apply(in)
+			},
+			V: []*anydiff.Var{in},
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestAvgPool2D(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		pool := &anydiff.AvgPool2D{
+			InWidth:  5,
+			InHeight: 4,
+			InDepth:  2,
+			Width:    2,
+			Height:   3,
+		}
+		if pool.OutWidth() != 2 || pool.OutHeight() != 1 {
+			t.Errorf("bad output size %dx%d", pool.OutWidth(), pool.OutHeight())
+		}
+		in := makeRandomVec(c, 2*5*4*2)
+		actual := getComponents(pool.Apply(in).Output())
+		expected := naivePool(getComponents(in.Vector), 5, 4, 2, 2, 3, 2, 3,
+			func(x []float64) float64 {
+				var sum float64
+				for _, y := range x {
+					sum += y
+				}
+				return sum / float64(len(x))
+			})
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return pool.Apply(in)
+			},
+			V: []*anydiff.Var{in},
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func testConv2D() *anydiff.Conv2D {
+	return &anydiff.Conv2D{
+		InWidth:      6,
+		InHeight:     5,
+		InDepth:      2,
+		FilterWidth:  3,
+		FilterHeight: 2,
+		StrideX:      2,
+		PadX:         1,
+		PadY:         2,
+		DilationY:    2,
+	}
+}
+
+func naiveConv2D(conv *anydiff.Conv2D, in, filters []float64) []float64 {
+	strideX, strideY := nonZero(conv.StrideX), nonZero(conv.StrideY)
+	dilX, dilY := nonZero(conv.DilationX), nonZero(conv.DilationY)
+	filterSize := conv.FilterWidth * conv.FilterHeight * conv.InDepth
+	numFilters := len(filters) / filterSize
+	n := len(in) / (conv.InWidth * conv.InHeight * conv.InDepth)
+	var res []float64
+	for b := 0; b < n; b++ {
+		for y := 0; y < conv.OutHeight(); y++ {
+			for x := 0; x < conv.OutWidth(); x++ {
+				for f := 0; f < numFilters; f++ {
+					var sum float64
+					for fy := 0; fy < conv.FilterHeight; fy++ {
+						for fx := 0; fx < conv.FilterWidth; fx++ {
+							inY := y*strideY - conv.PadY + fy*dilY
+							inX := x*strideX - conv.PadX + fx*dilX
+							if inX < 0 || inY < 0 || inX >= conv.InWidth ||
+								inY >= conv.InHeight {
+								continue
+							}
+							for z := 0; z < conv.InDepth; z++ {
+								inIdx := ((b*conv.InHeight+inY)*conv.InWidth+inX)*
+									conv.InDepth + z
+								filterIdx := f*filterSize +
+									(fy*conv.FilterWidth+fx)*conv.InDepth + z
+								sum += in[inIdx] * filters[filterIdx]
+							}
+						}
+					}
+					res = append(res, sum)
+				}
+			}
+		}
+	}
+	return res
+}
+
+func naivePool(in []float64, width, height, depth, poolW, poolH, strideX,
+	strideY int, f func([]float64) float64) []float64 {
+	outW := (width-poolW)/strideX + 1
+	outH := (height-poolH)/strideY + 1
+	n := len(in) / (width * height * depth)
+	var res []float64
+	for b := 0; b < n; b++ {
+		for y := 0; y < outH; y++ {
+			for x := 0; x < outW; x++ {
+				for z := 0; z < depth; z++ {
+					var window []float64
+					for py := 0; py < poolH; py++ {
+						for px := 0; px < poolW; px++ {
+							idx := ((b*height+y*strideY+py)*width+x*strideX+px)*depth + z
+							window = append(window, in[idx])
+						}
+					}
+					res = append(res, f(window))
+				}
+			}
+		}
+	}
+	return res
+}
+
+func nonZero(x int) int {
+	if x == 0 {
+		return 1
+	}
+	return x
+}
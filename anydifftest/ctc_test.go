@@ -0,0 +1,138 @@
+package anydifftest
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestCTCLossOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, _ := makeBasicTestSeqs(c)
+		logProbs := ctcTestLogProbs(inSeq)
+		labels := [][]int{{1, 1}, {2}, {0, 3, 3}, {}}
+		actual := getComponents(anyseq.CTCLoss(logProbs, labels).Output())
+
+		var expected []float64
+		for i, seq := range ctcTestValues(logProbs) {
+			probs := bruteForceLabelings(seq)
+			expected = append(expected, -math.Log(probs[fmt.Sprint(labels[i])]))
+		}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+
+		impossible := [][]int{{1, 1, 1}, {2, 2}, {0}, {1}}
+		actual = getComponents(anyseq.CTCLoss(logProbs, impossible).Output())
+		if !math.IsInf(actual[0], 1) || !math.IsInf(actual[1], 1) {
+			t.Errorf("expected infinite losses but got %v", actual)
+		}
+	})
+}
+
+func TestCTCLossProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		if _, ok := c.MakeNumeric(3.14).(float32); ok {
+			t.Skip("need more testing precision")
+		}
+		inSeq, varList := makeBasicTestSeqs(c)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				labels := [][]int{{1, 1}, {2}, {0, 3, 3}, {}}
+				return anyseq.CTCLoss(ctcTestLogProbs(inSeq), labels)
+			},
+			V: varList,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestCTCDecoders(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		// Classes are 0, 1, and blank.
+		seq := [][]float64{
+			{0.1, 0.2, 0.7},
+			{0.6, 0.1, 0.3},
+			{0.6, 0.1, 0.3},
+			{0.2, 0.3, 0.5},
+			{0.5, 0.1, 0.4},
+			{0.1, 0.8, 0.1},
+		}
+		var vecs []anyvec.Vector
+		for _, x := range seq {
+			logs := make([]float64, len(x))
+			for i, p := range x {
+				logs[i] = math.Log(p)
+			}
+			vecs = append(vecs, makeVecData(c, logs...))
+		}
+		in := anyseq.ConstSeqList(c, [][]anyvec.Vector{vecs, vecs[:2]})
+
+		bestPath := anyseq.CTCBestPath(in)
+		if fmt.Sprint(bestPath) != "[[0 0 1] [0]]" {
+			t.Errorf("unexpected best path: %v", bestPath)
+		}
+
+		beam := anyseq.CTCPrefixBeamSearch(in, 1000)
+		for i, seq := range ctcTestValues(in) {
+			probs := bruteForceLabelings(seq)
+			var best string
+			for labels, prob := range probs {
+				if best == "" || prob > probs[best] {
+					best = labels
+				}
+			}
+			if fmt.Sprint(beam[i]) != best {
+				t.Errorf("sequence %d: expected %s but got %v", i, best, beam[i])
+			}
+		}
+	})
+}
+
+func ctcTestLogProbs(inSeq anyseq.Seq) anyseq.Seq {
+	return anyseq.Map(inSeq, func(v anydiff.Res, n int) anydiff.Res {
+		return anydiff.LogSoftmax(v, 6)
+	})
+}
+
+func ctcTestValues(s anyseq.Seq) [][][]float64 {
+	var res [][][]float64
+	for _, seq := range anyseq.SeparateSeqs(s.Output()) {
+		var vals [][]float64
+		for _, x := range seq {
+			vals = append(vals, getComponents(x))
+		}
+		res = append(res, vals)
+	}
+	return res
+}
+
+// bruteForceLabelings computes the probability of every
+// labeling by enumerating all of the alignments.
+func bruteForceLabelings(seq [][]float64) map[string]float64 {
+	res := map[string]float64{}
+	var recurse func(t int, path []int, logProb float64)
+	recurse = func(t int, path []int, logProb float64) {
+		if t == len(seq) {
+			labels := []int{}
+			last := -1
+			for _, k := range path {
+				if k != last && k != len(seq[0])-1 {
+					labels = append(labels, k)
+				}
+				last = k
+			}
+			res[fmt.Sprint(labels)] += math.Exp(logProb)
+			return
+		}
+		for k, p := range seq[t] {
+			recurse(t+1, append(path, k), logProb+p)
+		}
+	}
+	recurse(0, nil, 0)
+	return res
+}
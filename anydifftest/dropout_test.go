@@ -0,0 +1,101 @@
+package anydifftest
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestDropoutOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := makeRandomVec(c, 1000)
+		out := anydiff.Dropout(v, 0.25, rand.New(rand.NewSource(1337)), true)
+		actual := getComponents(out.Output())
+		var numDropped int
+		for i, x := range getComponents(v.Vector) {
+			if actual[i] == 0 {
+				numDropped++
+			} else if !valuesClose(actual[i], x/0.75, prec) {
+				t.Fatalf("component %d: expected %f but got %f", i, x/0.75, actual[i])
+			}
+		}
+		if numDropped < 200 || numDropped > 300 {
+			t.Errorf("unexpected number of dropped components: %d", numDropped)
+		}
+
+		same := anydiff.Dropout(v, 0.25, rand.New(rand.NewSource(1337)), true)
+		if !vectorsClose(getComponents(same.Output()), actual, prec) {
+			t.Error("same seed should produce the same mask")
+		}
+
+		if anydiff.Dropout(v, 0.25, nil, false) != anydiff.Res(v) {
+			t.Error("inference mode should be the identity")
+		}
+	})
+}
+
+func TestDropoutProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := makeRandomVec(c, 18)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				r := rand.New(rand.NewSource(1337))
+				return anydiff.Tanh(anydiff.Dropout(v, 0.5, r, true))
+			},
+			V: []*anydiff.Var{v},
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestGaussianNoise(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := makeRandomVec(c, 18)
+		out := anydiff.GaussianNoise(v, 0.5, rand.New(rand.NewSource(1337)), true)
+		same := anydiff.GaussianNoise(v, 0.5, rand.New(rand.NewSource(1337)), true)
+		if !vectorsClose(getComponents(out.Output()), getComponents(same.Output()), prec) {
+			t.Error("same seed should produce the same noise")
+		}
+		if vectorsClose(getComponents(out.Output()), getComponents(v.Vector), prec) {
+			t.Error("noise should change the output")
+		}
+		if anydiff.GaussianNoise(v, 0.5, nil, false) != anydiff.Res(v) {
+			t.Error("inference mode should be the identity")
+		}
+
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				r := rand.New(rand.NewSource(1337))
+				return anydiff.Sin(anydiff.GaussianNoise(v, 0.5, r, true))
+			},
+			V: []*anydiff.Var{v},
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestDropConnect(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		weights := makeRandomVec(c, 12)
+		in := makeRandomVec(c, 8)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				r := rand.New(rand.NewSource(1337))
+				m := &anydiff.Matrix{Data: weights, Rows: 3, Cols: 4}
+				dropped := anydiff.DropConnect(m, 0.5, r, true)
+				return anydiff.MatMul(false, true,
+					&anydiff.Matrix{Data: in, Rows: 2, Cols: 4}, dropped).Data
+			},
+			V:    []*anydiff.Var{weights, in},
+			Prec: prec * 4,
+		}
+		ch.FullCheck(t)
+
+		m := &anydiff.Matrix{Data: weights, Rows: 3, Cols: 4}
+		if anydiff.DropConnect(m, 0.5, nil, false).Data != anydiff.Res(weights) {
+			t.Error("inference mode should be the identity")
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package anydifftest
+
+import (
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestEmbeddingOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		table := anydiff.NewVar(makeVecData(c, 1, 2, 3, 4, 5, 6, 7, 8))
+		actual := getComponents(anydiff.Embedding(table, 2, []int{3, 0, 3}).Output())
+		expected := []float64{7, 8, 1, 2, 7, 8}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestEmbeddingProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		table := makeRandomVec(c, 15)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anydiff.Tanh(anydiff.Embedding(table, 3, []int{1, 4, 1, 0}))
+			},
+			V: []*anydiff.Var{table},
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestEmbeddingRowGrad(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		table := makeRandomVec(c, 15)
+		out := anydiff.Embedding(table, 3, []int{1, 4, 1})
+		upstream := makeRandomVec(c, 9).Vector
+
+		dense := anydiff.NewGrad(table)
+		out.Propagate(upstream.Copy(), dense)
+
+		sparse := anydiff.Grad{table: anydiff.NewRowGrad(table, 3)}
+		out.Propagate(upstream.Copy(), sparse)
+		anydiff.Embedding(table, 3, []int{4}).Propagate(upstream.Slice(0, 3), sparse)
+		anydiff.Embedding(table, 3, []int{4}).Propagate(upstream.Slice(0, 3), dense)
+
+		rg := sparse[table].(*anydiff.RowGrad)
+		if len(rg.Indices) != 2 || rg.Indices[0] != 1 || rg.Indices[1] != 4 {
+			t.Errorf("unexpected indices: %v", rg.Indices)
+		}
+		if !vectorsClose(getComponents(rg.Dense()), getComponents(dense[table]), prec) {
+			t.Errorf("expected %v but got %v", getComponents(dense[table]),
+				getComponents(rg.Dense()))
+		}
+
+		expectedDot := dense.Dot(dense)
+		if actual := sparse.Dot(dense); !valuesClose(actual, expectedDot, prec) {
+			t.Errorf("expected dot %f but got %f", expectedDot, actual)
+		}
+		if actual := sparse.Dot(sparse); !valuesClose(actual, expectedDot, prec) {
+			t.Errorf("expected self dot %f but got %f", expectedDot, actual)
+		}
+
+		expected := table.Vector.Copy()
+		expected.Add(dense[table])
+		sparse.AddToVars()
+		if !vectorsClose(getComponents(table.Vector), getComponents(expected), prec) {
+			t.Errorf("expected %v but got %v", getComponents(expected),
+				getComponents(table.Vector))
+		}
+	})
+}
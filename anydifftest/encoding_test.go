@@ -0,0 +1,225 @@
+package anydifftest
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anydiff/anyfwd"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/anyvec/anyvec64"
+)
+
+func TestNamedVarsEncoding(t *testing.T) {
+	encoders := map[string]func(n anydiff.NamedVars) ([]byte, error){
+		"Binary": anydiff.NamedVars.MarshalBinary,
+		"JSON": func(n anydiff.NamedVars) ([]byte, error) {
+			return json.Marshal(n)
+		},
+		"Gob": func(n anydiff.NamedVars) ([]byte, error) {
+			var buf bytes.Buffer
+			err := gob.NewEncoder(&buf).Encode(n)
+			return buf.Bytes(), err
+		},
+	}
+	decoders := map[string]func(data []byte, n *anydiff.NamedVars) error{
+		"Binary": func(data []byte, n *anydiff.NamedVars) error {
+			return n.UnmarshalBinary(data)
+		},
+		"JSON": func(data []byte, n *anydiff.NamedVars) error {
+			return json.Unmarshal(data, n)
+		},
+		"Gob": func(data []byte, n *anydiff.NamedVars) error {
+			return gob.NewDecoder(bytes.NewReader(data)).Decode(n)
+		},
+	}
+	for name, encoder := range encoders {
+		decoder := decoders[name]
+		t.Run(name, func(t *testing.T) {
+			vars := anydiff.NamedVars{
+				"weights": anydiff.NewVar(anyvec32.MakeVectorData([]float32{1.5, -2, 0.1})),
+				"biases":  anydiff.NewVar(anyvec64.MakeVectorData([]float64{0.1, 3})),
+			}
+			data, err := encoder(vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			weights := anydiff.NewVar(anyvec32.MakeVector(3))
+			biases := anydiff.NewVar(anyvec64.MakeVector(2))
+			existing := anydiff.NamedVars{"weights": weights, "biases": biases}
+			if err := decoder(data, &existing); err != nil {
+				t.Fatal(err)
+			}
+			if existing["weights"] != weights || existing["biases"] != biases {
+				t.Error("existing variable was replaced")
+			}
+			for name, v := range vars {
+				expected := v.Vector.Data()
+				actual := existing[name].Vector.Data()
+				if !reflect.DeepEqual(actual, expected) {
+					t.Errorf("variable %s: expected %v but got %v", name, expected, actual)
+				}
+			}
+
+			missing := anydiff.NamedVars{"weights": anydiff.NewVar(anyvec32.MakeVector(3))}
+			if err := decoder(data, &missing); err == nil {
+				t.Error("expected error for missing variable")
+			}
+
+			wrongSize := anydiff.NamedVars{"weights": anydiff.NewVar(anyvec32.MakeVector(2))}
+			if err := decoder(data, &wrongSize); err == nil {
+				t.Error("expected error for size mismatch")
+			}
+		})
+	}
+}
+
+func TestNamedVarsDecode(t *testing.T) {
+	vars := anydiff.NamedVars{
+		"weights": anydiff.NewVar(anyvec32.MakeVectorData([]float32{1.5, -2, 0.1})),
+		"biases":  anydiff.NewVar(anyvec64.MakeVectorData([]float64{0.1, 3})),
+	}
+	binaryData, err := vars.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonData, err := json.Marshal(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"Binary": binaryData, "JSON": jsonData} {
+		t.Run(name, func(t *testing.T) {
+			var fresh anydiff.NamedVars
+			if err := fresh.Decode(anyvec64.DefaultCreator{}, data); err != nil {
+				t.Fatal(err)
+			}
+			if len(fresh) != 2 {
+				t.Fatalf("expected 2 variables but got %d", len(fresh))
+			}
+			expected := map[string][]float64{
+				"weights": {float64(float32(1.5)), float64(float32(-2)), float64(float32(0.1))},
+				"biases":  {0.1, 3},
+			}
+			for name, x := range expected {
+				actual := fresh[name].Vector.Data()
+				if !reflect.DeepEqual(actual, x) {
+					t.Errorf("variable %s: expected %v but got %v", name, x, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestNamedVarsTypeMismatch(t *testing.T) {
+	vars := anydiff.NamedVars{"x": anydiff.NewVar(anyvec64.MakeVectorData([]float64{0.1, 3}))}
+	binaryData, err := vars.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonData, err := json.Marshal(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"Binary": binaryData, "JSON": jsonData} {
+		t.Run(name, func(t *testing.T) {
+			existing := anydiff.NamedVars{"x": anydiff.NewVar(anyvec32.MakeVector(2))}
+			if err := existing.Decode(nil, data); err == nil {
+				t.Error("expected error loading float64 into float32 variable")
+			}
+			var fresh anydiff.NamedVars
+			if err := fresh.Decode(anyvec32.DefaultCreator{}, data); err == nil {
+				t.Error("expected error decoding float64 with float32 creator")
+			}
+		})
+	}
+	if err := json.Unmarshal(jsonData, &anydiff.NamedVars{
+		"x": anydiff.NewVar(anyvec32.MakeVector(2)),
+	}); err == nil {
+		t.Error("expected error from UnmarshalJSON")
+	}
+}
+
+func TestNamedVarsNonFinite(t *testing.T) {
+	expected := []float64{math.NaN(), math.Inf(1), math.Inf(-1), 2.5}
+	vars := anydiff.NamedVars{"x": anydiff.NewVar(anyvec64.MakeVectorData(expected))}
+	data, err := json.Marshal(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := anydiff.NamedVars{"x": anydiff.NewVar(anyvec64.MakeVector(4))}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	actual := decoded["x"].Vector.Data().([]float64)
+	if !math.IsNaN(actual[0]) || !reflect.DeepEqual(actual[1:], expected[1:]) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+}
+
+func TestNamedVarsEncodingErrors(t *testing.T) {
+	var n anydiff.NamedVars
+	if err := n.UnmarshalBinary([]byte("not a checkpoint")); err == nil {
+		t.Error("expected error for bad magic")
+	}
+	vars := anydiff.NamedVars{"x": anydiff.NewVar(anyvec32.MakeVector(3))}
+	data, _ := vars.MarshalBinary()
+	if err := n.UnmarshalBinary(data[:len(data)-2]); err == nil {
+		t.Error("expected error for truncated data")
+	}
+
+	c := &anyfwd.Creator{ValueCreator: anyvec64.DefaultCreator{}, GradSize: 1}
+	fwdVars := anydiff.NamedVars{"x": anydiff.NewVar(c.MakeVector(3))}
+	if _, err := fwdVars.MarshalBinary(); err == nil {
+		t.Error("expected error for anyfwd vector")
+	}
+}
+
+func TestNamedGradEncoding(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v1 := anydiff.NewVar(c.MakeVector(2))
+		v2 := anydiff.NewVar(c.MakeVector(3))
+		names := anydiff.NamedVars{"v1": v1, "v2": v2}
+		grad := anydiff.Grad{v2: c.MakeVectorData(c.MakeNumericList([]float64{1, 2, 3}))}
+
+		data, err := json.Marshal(&anydiff.NamedGrad{Names: names, Grad: grad})
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &anydiff.NamedGrad{Names: names}
+		if err := json.Unmarshal(data, decoded); err != nil {
+			t.Fatal(err)
+		}
+		if len(decoded.Grad) != 1 {
+			t.Fatalf("expected 1 variable but got %d", len(decoded.Grad))
+		}
+		actual := getComponents(decoded.Grad[v2])
+		if !vectorsClose(actual, []float64{1, 2, 3}, prec) {
+			t.Errorf("expected [1 2 3] but got %v", actual)
+		}
+
+		data, err = (&anydiff.NamedGrad{Names: names, Grad: grad}).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		existing := anydiff.NewGrad(v1, v2)
+		decoded = &anydiff.NamedGrad{Names: names, Grad: existing}
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		actual = getComponents(existing[v2])
+		if !vectorsClose(actual, []float64{1, 2, 3}, prec) {
+			t.Errorf("expected [1 2 3] but got %v", actual)
+		}
+
+		unknown := &anydiff.NamedGrad{Names: anydiff.NamedVars{"v1": v1}}
+		if err := unknown.UnmarshalBinary(data); err == nil {
+			t.Error("expected error for unknown variable")
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package anydifftest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestGradArithmetic(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v1 := anydiff.NewVar(c.MakeVector(2))
+		v2 := anydiff.NewVar(c.MakeVector(1))
+		v3 := anydiff.NewVar(c.MakeVector(3))
+		makeVec := func(vals ...float64) anyvec.Vector {
+			return c.MakeVectorData(c.MakeNumericList(vals))
+		}
+		g1 := anydiff.Grad{v1: makeVec(1, 2), v2: makeVec(-2)}
+		g2 := anydiff.Grad{v1: makeVec(3, -1), v3: makeVec(1, 1, 2)}
+
+		if n := g1.Norm(); math.Abs(n-3) > prec {
+			t.Errorf("expected norm 3 but got %f", n)
+		}
+		if d := g1.Dot(g2); math.Abs(d-1) > prec {
+			t.Errorf("expected dot 1 but got %f", d)
+		}
+
+		sum := g1.Copy()
+		sum.Add(g2)
+		expected := map[*anydiff.Var][]float64{
+			v1: {4, 1},
+			v2: {-2},
+			v3: {1, 1, 2},
+		}
+		if len(sum) != len(expected) {
+			t.Fatalf("expected %d variables but got %d", len(expected), len(sum))
+		}
+		for v, x := range expected {
+			if actual := getComponents(sum[v]); !vectorsClose(actual, x, prec) {
+				t.Errorf("expected %v but got %v", x, actual)
+			}
+		}
+
+		// Make sure the copy and the addition did not
+		// modify the original gradients.
+		if actual := getComponents(g1[v1]); !vectorsClose(actual, []float64{1, 2}, prec) {
+			t.Errorf("original modified: %v", actual)
+		}
+		g2[v3].Scale(c.MakeNumeric(2))
+		if actual := getComponents(sum[v3]); !vectorsClose(actual, []float64{1, 1, 2}, prec) {
+			t.Errorf("added vector was not copied: %v", actual)
+		}
+	})
+}
+
+func TestGradClipping(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v1 := anydiff.NewVar(c.MakeVector(2))
+		v2 := anydiff.NewVar(c.MakeVector(2))
+		makeGrad := func() anydiff.Grad {
+			return anydiff.Grad{
+				v1: c.MakeVectorData(c.MakeNumericList([]float64{3, 4})),
+				v2: c.MakeVectorData(c.MakeNumericList([]float64{0, 0.5})),
+			}
+		}
+
+		g := makeGrad()
+		oldNorm := g.ClipNorm(2)
+		if math.Abs(oldNorm-math.Sqrt(25.25)) > prec {
+			t.Errorf("unexpected old norm: %f", oldNorm)
+		}
+		if n := g.Norm(); math.Abs(n-2) > prec {
+			t.Errorf("expected norm 2 but got %f", n)
+		}
+
+		g = makeGrad()
+		g.ClipNorm(10)
+		if actual := getComponents(g[v1]); !vectorsClose(actual, []float64{3, 4}, prec) {
+			t.Errorf("unexpected clipping: %v", actual)
+		}
+
+		g = makeGrad()
+		g.ClipVarNorms(1)
+		if actual := getComponents(g[v1]); !vectorsClose(actual, []float64{0.6, 0.8}, prec) {
+			t.Errorf("expected [0.6 0.8] but got %v", actual)
+		}
+		if actual := getComponents(g[v2]); !vectorsClose(actual, []float64{0, 0.5}, prec) {
+			t.Errorf("expected [0 0.5] but got %v", actual)
+		}
+	})
+}
+
+func TestVJP(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v1 := makeRandomVec(c, 5)
+		v2 := makeRandomVec(c, 5)
+		unused := makeRandomVec(c, 3)
+		r := anydiff.Mul(anydiff.Tanh(v1), v2)
+		upstream := makeRandomVec(c, 5).Vector
+		upCopy := upstream.Copy()
+
+		g := anydiff.VJP(r, upstream, v1, unused)
+		if len(g) != 2 {
+			t.Fatalf("expected 2 entries but got %d", len(g))
+		}
+		if !vectorsClose(getComponents(upstream), getComponents(upCopy), prec) {
+			t.Error("upstream was modified")
+		}
+
+		expected1 := anydiff.Tanh(v1).Output().Copy()
+		expected1.Mul(expected1)
+		expected1.Scale(c.MakeNumeric(-1))
+		expected1.AddScalar(c.MakeNumeric(1))
+		expected1.Mul(v2.Vector)
+		expected1.Mul(upstream)
+		actual := getComponents(g[v1])
+		if expected := getComponents(expected1); !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+		if !vectorsClose(getComponents(g[unused]), make([]float64, 3), prec) {
+			t.Error("unused variable should have zero gradient")
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package anydifftest
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+type graphJSON struct {
+	Nodes []struct {
+		ID        int    `json:"id"`
+		Op        string `json:"op"`
+		Type      string `json:"type"`
+		OutputLen int    `json:"outputLen"`
+		Inputs    []int  `json:"inputs"`
+	} `json:"nodes"`
+}
+
+func TestNodeInfo(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v1 := makeRandomVec(c, 6)
+		v2 := makeRandomVec(c, 6)
+		sum := anydiff.Add(v1, v2)
+		info := sum.(anydiff.GraphNode).NodeInfo()
+		if info.Op != "Add" {
+			t.Errorf("expected op Add but got %s", info.Op)
+		}
+		if info.OutputLen != 6 {
+			t.Errorf("expected output length 6 but got %d", info.OutputLen)
+		}
+		if len(info.Inputs) != 2 || info.Inputs[0] != v1 || info.Inputs[1] != v2 {
+			t.Errorf("unexpected inputs: %v", info.Inputs)
+		}
+	})
+}
+
+func TestWriteGraphJSON(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := makeRandomVec(c, 6)
+		out := anydiff.Pool(anydiff.Tanh(v), func(r anydiff.Res) anydiff.Res {
+			return anydiff.Mul(r, r)
+		})
+
+		var buf bytes.Buffer
+		if err := anydiff.WriteGraphJSON(&buf, out); err != nil {
+			t.Fatal(err)
+		}
+		var graph graphJSON
+		if err := json.Unmarshal(buf.Bytes(), &graph); err != nil {
+			t.Fatal(err)
+		}
+
+		var ops []string
+		for i, node := range graph.Nodes {
+			if node.ID != i {
+				t.Errorf("node %d has ID %d", i, node.ID)
+			}
+			for _, in := range node.Inputs {
+				if in >= i {
+					t.Errorf("node %d has input %d out of order", i, in)
+				}
+			}
+			if node.OutputLen != 6 {
+				t.Errorf("node %d has output length %d", i, node.OutputLen)
+			}
+			ops = append(ops, node.Op)
+		}
+		expected := []string{"Var", "Tanh", "Var", "Mul", "Pool"}
+		if strings.Join(ops, ",") != strings.Join(expected, ",") {
+			t.Errorf("expected ops %v but got %v", expected, ops)
+		}
+	})
+}
+
+func TestWriteDOT(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, _ := makeBasicTestSeqs(c)
+		out := anyseq.Sum(anyseq.Map(inSeq, func(v anydiff.Res, n int) anydiff.Res {
+			return anydiff.Sigmoid(v)
+		}))
+
+		var buf bytes.Buffer
+		if err := anydiff.WriteDOT(&buf, out); err != nil {
+			t.Fatal(err)
+		}
+		dot := buf.String()
+		if !strings.HasPrefix(dot, "digraph G {\n") || !strings.HasSuffix(dot, "}\n") {
+			t.Errorf("unexpected DOT output: %s", dot)
+		}
+		for _, op := range []string{"Sum", "Map", "Sigmoid", "ResSeq"} {
+			if !strings.Contains(dot, "\""+op+"\\n") {
+				t.Errorf("missing op %s in DOT output: %s", op, dot)
+			}
+		}
+		if !strings.Contains(dot, " -> ") {
+			t.Errorf("missing edges in DOT output: %s", dot)
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package anydifftest
+
+import (
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestSoftmaxOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := makeVecData(c, 1, 2, 3, -1, 0.5, 2)
+		actual := getComponents(anydiff.Softmax(anydiff.NewConst(v), 3).Output())
+		expected := []float64{0.0900305732, 0.2447284711, 0.6652409558,
+			0.0391125733, 0.1752903921, 0.7855970346}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestSoftmaxProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := makeRandomVec(c, 18)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anydiff.Softmax(v, 6)
+			},
+			V: []*anydiff.Var{v},
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestSoftmaxCrossEntropyOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		logits := anydiff.NewConst(makeVecData(c, 1, 2, 3, -1, 0.5, 2))
+		targets := makeVecData(c, 0, 0.5, 0.5, 1, 0, 0)
+		weights := makeVecData(c, 2, 0.5)
+		actual := getComponents(anydiff.SoftmaxCrossEntropy(logits, targets, 3,
+			weights).Output())
+		expected := []float64{1.8152119289, 1.6206556483}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestSoftmaxCrossEntropyProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		if _, ok := c.MakeNumeric(3.14).(float32); ok {
+			t.Skip("need more testing precision")
+		}
+		v := makeRandomVec(c, 18)
+		targets := anydiff.Softmax(makeRandomVec(c, 18), 6).Output()
+		for _, weights := range []anyvec.Vector{nil, makeVecData(c, 1, -0.5, 2)} {
+			ch := &ResChecker{
+				F: func() anydiff.Res {
+					return anydiff.SoftmaxCrossEntropy(v, targets, 6, weights)
+				},
+				V: []*anydiff.Var{v},
+			}
+			ch.FullCheck(t)
+		}
+	})
+}
+
+func TestNLLOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		logProbs := anydiff.NewConst(makeVecData(c, -1, -2, -3, -4, -5, -6))
+		actual := getComponents(anydiff.NLL(logProbs, []int{2, 0}, 3,
+			makeVecData(c, 1, 0.5)).Output())
+		expected := []float64{3, 2}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+
+	})
+}
+
+func TestNLLProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		if _, ok := c.MakeNumeric(3.14).(float32); ok {
+			t.Skip("need more testing precision")
+		}
+		v := makeRandomVec(c, 18)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anydiff.NLL(anydiff.LogSoftmax(v, 6), []int{1, 5, 0}, 6, nil)
+			},
+			V: []*anydiff.Var{v},
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestBCEWithLogitsOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		logits := anydiff.NewConst(makeVecData(c, 1, -2, 30, -30))
+		targets := makeVecData(c, 1, 0, 0.5, 1)
+		actual := getComponents(anydiff.BCEWithLogits(logits, targets, 2, nil).Output())
+		expected := []float64{0.4401896986, 45}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestBCEWithLogitsProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		if _, ok := c.MakeNumeric(3.14).(float32); ok {
+			t.Skip("need more testing precision")
+		}
+		v := makeRandomVec(c, 18)
+		targets := anydiff.Sigmoid(makeRandomVec(c, 18)).Output()
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anydiff.BCEWithLogits(v, targets, 6, makeVecData(c, 1, -0.5, 0.5))
+			},
+			V: []*anydiff.Var{v},
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestHingeOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		scores := anydiff.NewConst(makeVecData(c, 1, 2, 0.5, 3, -1, 2.5))
+		actual := getComponents(anydiff.Hinge(scores, []int{0, 2}, 3, nil).Output())
+		expected := []float64{2.5, 1.5}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+
+	})
+}
+
+func TestHingeProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := makeRandomVec(c, 18)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anydiff.Hinge(v, []int{3, 0, 4}, 6, makeVecData(c, 1, 2, 3))
+			},
+			V: []*anydiff.Var{v},
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestHuberOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		preds := anydiff.NewConst(makeVecData(c, 0.5, 3, -2, 0.1))
+		targets := makeVecData(c, 0, 0, 0, 1)
+		actual := getComponents(anydiff.Huber(preds, targets, 2, 1, nil).Output())
+		expected := []float64{2.625, 1.905}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+
+	})
+}
+
+func TestHuberProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		if _, ok := c.MakeNumeric(3.14).(float32); ok {
+			t.Skip("need more testing precision")
+		}
+		v := makeRandomVec(c, 18)
+		targets := makeRandomVec(c, 18).Vector
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anydiff.Huber(v, targets, 6, 0.5, makeVecData(c, 1, 2, 3))
+			},
+			V: []*anydiff.Var{v},
+		}
+		ch.FullCheck(t)
+	})
+}
@@ -43,6 +43,64 @@ func TestSigmoidProp(t *testing.T) {
 	testMathFunction(t, anydiff.Sigmoid)
 }
 
+func TestLog(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := makeDivisionFriendlyVec(c, 18)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anydiff.Log(v)
+			},
+			V: []*anydiff.Var{v},
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestLog1p(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := makeDivisionFriendlyVec(c, 18)
+		v.Vector.AddScalar(c.MakeNumeric(-0.5))
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anydiff.Log1p(v)
+			},
+			V: []*anydiff.Var{v},
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestLog1pSmall(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inputs := []float64{1e-10, -1e-10, 3e-7, 0, 0.5}
+		in := anydiff.NewConst(makeVecData(c, inputs...))
+		actual := getComponents(anydiff.Log1p(in).Output())
+		for i, x := range inputs {
+			expected := math.Log1p(x)
+			if math.Abs(actual[i]-expected) > 1e-6*math.Abs(expected) {
+				t.Errorf("log1p(%g): expected %g but got %g", x, expected, actual[i])
+			}
+		}
+	})
+}
+
+func TestSoftplusOut(t *testing.T) {
+	inVec := anyvec32.MakeVectorData([]float32{1000, -1000, 2, -2, 0})
+	inRes := anydiff.NewConst(inVec)
+	actual := anydiff.Softplus(inRes).Output().Data().([]float32)
+	expected := []float32{1000, 0, 2.126928011, 0.126928011, 0.6931471806}
+	for i, x := range expected {
+		a := actual[i]
+		if math.IsNaN(float64(a)) || math.Abs(float64(x-a)) > 1e-3 {
+			t.Errorf("expected %f but got %f", x, a)
+		}
+	}
+}
+
+func TestSoftplusProp(t *testing.T) {
+	testMathFunction(t, anydiff.Softplus)
+}
+
 func TestLogSigmoidOut(t *testing.T) {
 	inVec := anyvec32.MakeVectorData([]float32{1000, -1000, 2, -2, 0})
 	inRes := anydiff.NewConst(inVec)
@@ -76,6 +134,32 @@ func TestLogSoftmax(t *testing.T) {
 	})
 }
 
+func TestLogSumExpOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := c.MakeVectorData(c.MakeNumericList([]float64{
+			1, 2, 3, -1000, 1000, 999,
+		}))
+		actual := getComponents(anydiff.LogSumExp(anydiff.NewConst(v), 3).Output())
+		expected := []float64{3.4076059644, 1000.3132616875}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestLogSumExpProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := makeRandomVec(c, 18)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anydiff.LogSumExp(v, 6)
+			},
+			V: []*anydiff.Var{v},
+		}
+		ch.FullCheck(t)
+	})
+}
+
 func TestSquare(t *testing.T) {
 	testMathFunction(t, anydiff.Square)
 }
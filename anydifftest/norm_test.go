@@ -0,0 +1,115 @@
+package anydifftest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestLayerNormOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := anydiff.NewConst(makeVecData(c, 1, 2, 3, -2, 0, 8))
+		actual := getComponents(anydiff.LayerNorm(v, 3, 0.01).Output())
+		var expected []float64
+		for _, chunk := range [][]float64{{1, 2, 3}, {-2, 0, 8}} {
+			mean := (chunk[0] + chunk[1] + chunk[2]) / 3
+			var variance float64
+			for _, x := range chunk {
+				variance += (x - mean) * (x - mean) / 3
+			}
+			for _, x := range chunk {
+				expected = append(expected, (x-mean)/math.Sqrt(variance+0.01))
+			}
+		}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestLayerNormProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		if _, ok := c.MakeNumeric(3.14).(float32); ok {
+			t.Skip("need more testing precision")
+		}
+		v := makeRandomVec(c, 18)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return anydiff.LayerNorm(v, 6, 1e-3)
+			},
+			V: []*anydiff.Var{v},
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestBatchNormOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		bn := anydiff.NewBatchNorm(c, 2)
+		bn.Epsilon = 0.01
+		bn.Momentum = 0.5
+		m := &anydiff.Matrix{
+			Data: anydiff.NewConst(makeVecData(c, 1, -2, 2, 0, 3, 8)),
+			Rows: 3,
+			Cols: 2,
+		}
+		actual := getComponents(bn.Apply(m).Data.Output())
+
+		means := []float64{2, 2}
+		variances := []float64{2.0 / 3, 56.0 / 3}
+		var expected []float64
+		for i, x := range []float64{1, -2, 2, 0, 3, 8} {
+			expected = append(expected, (x-means[i%2])/math.Sqrt(variances[i%2]+0.01))
+		}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+
+		expectedMean := []float64{1, 1}
+		expectedVar := []float64{0.5 + variances[0]/2, 0.5 + variances[1]/2}
+		if !vectorsClose(getComponents(bn.RunningMean), expectedMean, prec) {
+			t.Errorf("expected running mean %v but got %v", expectedMean,
+				getComponents(bn.RunningMean))
+		}
+		if !vectorsClose(getComponents(bn.RunningVar), expectedVar, prec) {
+			t.Errorf("expected running variance %v but got %v", expectedVar,
+				getComponents(bn.RunningVar))
+		}
+
+		bn.Training = false
+		actual = getComponents(bn.Apply(m).Data.Output())
+		expected = nil
+		for i, x := range []float64{1, -2, 2, 0, 3, 8} {
+			mean, variance := expectedMean[i%2], expectedVar[i%2]
+			expected = append(expected, (x-mean)/math.Sqrt(variance+0.01))
+		}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected inference output %v but got %v", expected, actual)
+		}
+		if !vectorsClose(getComponents(bn.RunningMean), expectedMean, prec) {
+			t.Error("inference should not update running statistics")
+		}
+	})
+}
+
+func TestBatchNormProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		if _, ok := c.MakeNumeric(3.14).(float32); ok {
+			t.Skip("need more testing precision")
+		}
+		v := makeRandomVec(c, 20)
+		for _, training := range []bool{true, false} {
+			bn := anydiff.NewBatchNorm(c, 4)
+			bn.Training = training
+			ch := &ResChecker{
+				F: func() anydiff.Res {
+					return bn.Apply(&anydiff.Matrix{Data: v, Rows: 5, Cols: 4}).Data
+				},
+				V: []*anydiff.Var{v},
+			}
+			ch.FullCheck(t)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package anydifftest
+
+import (
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// customMulSin computes x*y + sin(x) using NewOp.
+func customMulSin(x, y anydiff.Res) anydiff.Res {
+	out := x.Output().Copy()
+	out.Mul(y.Output())
+	sin := x.Output().Copy()
+	anyvec.Sin(sin)
+	out.Add(sin)
+	return anydiff.NewOp("MulSin", out, []anydiff.Res{x, y},
+		func(u anyvec.Vector, down []anyvec.Vector) {
+			if down[0] != nil {
+				down[0].Set(x.Output())
+				anyvec.Cos(down[0])
+				down[0].Add(y.Output())
+				down[0].Mul(u)
+			}
+			if down[1] != nil {
+				down[1].Set(u)
+				down[1].Mul(x.Output())
+			}
+		})
+}
+
+func TestNewOp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v1 := makeRandomVec(c, 8)
+		v2 := makeRandomVec(c, 8)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				return customMulSin(anydiff.Tanh(v1), v2)
+			},
+			V: []*anydiff.Var{v1, v2},
+		}
+		ch.FullCheck(t)
+
+		r := customMulSin(v1, v2)
+		if op := r.(anydiff.GraphNode).NodeInfo().Op; op != "MulSin" {
+			t.Errorf("expected op MulSin but got %s", op)
+		}
+	})
+}
+
+func TestNewOpNeeded(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		v := makeRandomVec(c, 4)
+		constant := anydiff.NewConst(c.MakeVector(4))
+		var calls int
+		var lastDown []anyvec.Vector
+		r := anydiff.NewOp("", c.MakeVector(4), []anydiff.Res{constant, v},
+			func(u anyvec.Vector, down []anyvec.Vector) {
+				calls++
+				lastDown = append([]anyvec.Vector{}, down...)
+			})
+		if len(r.Vars()) != 1 || !r.Vars().Has(v) {
+			t.Error("unexpected variable set")
+		}
+		r.Propagate(c.MakeVector(4), anydiff.Grad{})
+		if calls != 0 {
+			t.Error("backward should not be called without needed inputs")
+		}
+		r.Propagate(c.MakeVector(4), anydiff.NewGrad(v))
+		if calls != 1 || lastDown[0] != nil || lastDown[1] == nil {
+			t.Errorf("unexpected downstream vectors: %v", lastDown)
+		} else if lastDown[1].Len() != 4 || c.Float64(anyvec.AbsMax(lastDown[1])) != 0 {
+			t.Error("downstream vector should be zeroed")
+		}
+		if op := r.(anydiff.GraphNode).NodeInfo().Op; op != "Op" {
+			t.Errorf("expected default name Op but got %s", op)
+		}
+	})
+}
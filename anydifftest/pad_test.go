@@ -0,0 +1,89 @@
+package anydifftest
+
+import (
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestPadOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq := anyseq.ConstSeqList(c, [][]anyvec.Vector{
+			{makeVecData(c, 1, 2), makeVecData(c, 3, 4)},
+			{},
+			{makeVecData(c, 5, 6), makeVecData(c, 7, 8), makeVecData(c, 9, 10)},
+		})
+		padded, lengths := anyseq.Pad(inSeq, c.MakeNumeric(-1))
+		if len(lengths) != 3 || lengths[0] != 2 || lengths[1] != 0 || lengths[2] != 3 {
+			t.Fatalf("unexpected lengths: %v", lengths)
+		}
+		actual := getComponents(padded.Output())
+		expected := []float64{
+			1, 2, 3, 4, -1, -1,
+			-1, -1, -1, -1, -1, -1,
+			5, 6, 7, 8, 9, 10,
+		}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected %v but got %v", expected, actual)
+		}
+
+		actual = getComponents(anyseq.PadMask(c, lengths))
+		expected = []float64{1, 1, 0, 0, 0, 0, 1, 1, 1}
+		if !vectorsClose(actual, expected, prec) {
+			t.Errorf("expected mask %v but got %v", expected, actual)
+		}
+	})
+}
+
+func TestPadProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		ch := &ResChecker{
+			F: func() anydiff.Res {
+				padded, _ := anyseq.Pad(inSeq, c.MakeNumeric(2))
+				return padded
+			},
+			V: varList,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestUnpad(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, _ := makeBasicTestSeqs(c)
+		padded, lengths := anyseq.Pad(inSeq, c.MakeNumeric(3))
+		if !SeqsClose(anyseq.Unpad(padded, lengths), inSeq, prec) {
+			t.Error("round trip did not preserve sequences")
+		}
+
+		v := makeRandomVec(c, padded.Output().Len())
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.Unpad(v, lengths)
+			},
+			V: []*anydiff.Var{v},
+		}
+		ch.FullCheck(t)
+
+		// Padding positions should get a zero gradient.
+		out := anyseq.Unpad(v, lengths)
+		upstream := make([]*anyseq.Batch, len(out.Output()))
+		for i, x := range out.Output() {
+			u := c.MakeVector(x.Packed.Len())
+			u.AddScalar(c.MakeNumeric(1))
+			upstream[i] = &anyseq.Batch{Packed: u, Present: x.Present}
+		}
+		grad := anydiff.NewGrad(v)
+		out.Propagate(upstream, grad)
+		actual := getComponents(grad[v])
+		expected := getComponents(anyseq.PadMask(c, lengths))
+		for i, x := range actual {
+			if x != expected[i/6] {
+				t.Errorf("gradient %d: expected %f but got %f", i, expected[i/6], x)
+			}
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package anydifftest
+
+import (
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anydiff/anyfwd"
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestScanOut(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, _ := makeBasicTestSeqs(c)
+		init := makeRandomVec(c, 6)
+		actual := anyseq.Scan(inSeq, init,
+			func(state, in anydiff.Res, n int) (anydiff.Res, anydiff.Res) {
+				newState := anydiff.Add(anydiff.Scale(state, c.MakeNumeric(0.5)), in)
+				return newState, anydiff.Scale(newState, c.MakeNumeric(2))
+			})
+
+		var expected [][]anyvec.Vector
+		for _, seq := range anyseq.SeparateSeqs(inSeq.Output()) {
+			state := init.Vector.Copy()
+			var outs []anyvec.Vector
+			for _, in := range seq {
+				state.Scale(c.MakeNumeric(0.5))
+				state.Add(in)
+				out := state.Copy()
+				out.Scale(c.MakeNumeric(2))
+				outs = append(outs, out)
+			}
+			expected = append(expected, outs)
+		}
+		if !SeqsClose(actual, anyseq.ConstSeqList(c, expected), prec) {
+			t.Error("unexpected output")
+		}
+	})
+}
+
+func TestScanProp(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		if _, ok := c.MakeNumeric(3.14).(float32); ok {
+			t.Skip("need more testing precision")
+		}
+		inSeq, varList := makeBasicTestSeqs(c)
+		init := makeRandomVec(c, 4)
+		weights := makeRandomVec(c, 4)
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.Scan(inSeq, init,
+					func(state, in anydiff.Res, n int) (anydiff.Res, anydiff.Res) {
+						stateMat := &anydiff.Matrix{Data: state, Rows: n, Cols: 4}
+						inMat := &anydiff.Matrix{Data: in, Rows: n, Cols: 6}
+						combined := anydiff.Concat(anydiff.SumCols(inMat),
+							anydiff.SumCols(stateMat))
+						mixed := anydiff.Mul(anydiff.ScaleRepeated(state, weights),
+							state)
+						newState := anydiff.Tanh(anydiff.Add(mixed,
+							anydiff.Scale(anydiff.Concat(combined, combined),
+								c.MakeNumeric(0.1))))
+						return newState, anydiff.Sigmoid(newState)
+					})
+			},
+			V: append([]*anydiff.Var{init, weights}, varList...),
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestLSTM(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		if _, ok := c.MakeNumeric(3.14).(float32); ok {
+			t.Skip("need more testing precision")
+		}
+		inSeq, varList := makeBasicTestSeqs(c)
+		lstm := anyseq.NewLSTM(c, 6, 3)
+		anyvec.Rand(lstm.Init.Vector, anyvec.Normal, nil)
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return lstm.Apply(inSeq)
+			},
+			V: append(lstm.Parameters(), varList...),
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestGRU(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		if _, ok := c.MakeNumeric(3.14).(float32); ok {
+			t.Skip("need more testing precision")
+		}
+		inSeq, varList := makeBasicTestSeqs(c)
+		gru := anyseq.NewGRU(c, 6, 3)
+		anyvec.Rand(gru.Init.Vector, anyvec.Normal, nil)
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return gru.Apply(inSeq)
+			},
+			V: append(gru.Parameters(), varList...),
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestLSTMMakeFwd(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		lstm := anyseq.NewLSTM(c, 6, 3)
+		fc := &anyfwd.Creator{ValueCreator: c, GradSize: 1}
+		anyfwd.MakeFwd(fc, lstm)
+		for i, p := range lstm.Parameters() {
+			if _, ok := p.Vector.(*anyfwd.Vector); !ok {
+				t.Fatalf("parameter %d was not converted", i)
+			}
+		}
+		in := anyseq.ConstSeqList(fc, [][]anyvec.Vector{
+			{fc.MakeVector(6), fc.MakeVector(6)},
+			{fc.MakeVector(6)},
+		})
+		out := lstm.Apply(in).Output()
+		if len(out) != 2 || out[0].Packed.Len() != 6 || out[1].Packed.Len() != 3 {
+			t.Error("unexpected output shape")
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package anydifftest
+
+import (
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestScanStates(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		init := makeRandomVec(c, 24)
+		f := func(state, in anydiff.Res, n int) (anydiff.Res, anydiff.Res) {
+			return anydiff.Add(state, in), anydiff.Sin(state)
+		}
+
+		_, final := anyseq.ScanStates(inSeq, init, f)
+		expected := anydiff.Add(init, anyseq.SumEach(inSeq)).Output()
+		if !vectorsClose(getComponents(final), getComponents(expected), prec) {
+			t.Errorf("expected final states %v but got %v", getComponents(expected),
+				getComponents(final))
+		}
+
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				out, _ := anyseq.ScanStates(inSeq, init, f)
+				return out
+			},
+			V: append(varList, init),
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestTBPTT(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		block := anyseq.NewGRU(c, 6, 3)
+		anyvec.Rand(block.Init.Vector, anyvec.Normal, nil)
+		driver := &anyseq.TBPTT{
+			Init:  block.Init,
+			Block: block.ApplyStates,
+			Cost: func(out anyseq.Seq, start int) anydiff.Res {
+				return anyseq.Sum(out)
+			},
+		}
+
+		fullCost := anyseq.Sum(block.Apply(inSeq))
+		params := append(block.Parameters(), varList...)
+		fullGrad := anydiff.NewGrad(params...)
+		upstream := c.MakeVector(fullCost.Output().Len())
+		upstream.AddScalar(c.MakeNumeric(1))
+		fullCost.Propagate(upstream, fullGrad)
+		expectedCost := c.Float64(anyvec.Sum(fullCost.Output()))
+
+		// The forward pass should not depend on the window.
+		for _, window := range []int{1, 2, 4, 100} {
+			driver.Window = window
+			g := anydiff.NewGrad(params...)
+			cost := driver.Run(inSeq, g)
+			if !valuesClose(cost, expectedCost, prec) {
+				t.Errorf("window %d: expected cost %f but got %f", window, expectedCost, cost)
+			}
+			if window == 100 {
+				for _, v := range params {
+					if !vectorsClose(getComponents(g[v]), getComponents(fullGrad[v]), prec) {
+						t.Error("untruncated gradient should match full gradient")
+					}
+				}
+			}
+		}
+
+		// With truncation, the initial state only gets a
+		// gradient from the first window.
+		driver.Window = 2
+		g := anydiff.NewGrad(block.Init)
+		driver.Run(inSeq, g)
+		headCost := anyseq.Sum(block.Apply(anyseq.Head(inSeq, 2)))
+		headGrad := anydiff.NewGrad(block.Init)
+		headCost.Propagate(upstream.Copy(), headGrad)
+		if !vectorsClose(getComponents(g[block.Init]), getComponents(headGrad[block.Init]),
+			prec) {
+			t.Error("unexpected gradient for initial state")
+		}
+	})
+}
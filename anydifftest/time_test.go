@@ -0,0 +1,120 @@
+package anydifftest
+
+import (
+	"testing"
+
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestSeqSlice(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		for _, r := range [][2]int{{0, 2}, {1, 4}, {3, 10}, {2, 2}} {
+			actual := anyseq.Slice(inSeq, r[0], r[1])
+			var expected [][]anyvec.Vector
+			for _, seq := range anyseq.SeparateSeqs(inSeq.Output()) {
+				var sliced []anyvec.Vector
+				for i := r[0]; i < r[1] && i < len(seq); i++ {
+					sliced = append(sliced, seq[i])
+				}
+				expected = append(expected, sliced)
+			}
+			if !SeqsClose(actual, anyseq.ConstSeqList(c, expected), prec) {
+				t.Errorf("unexpected output for range %v", r)
+			}
+		}
+		if !SeqsClose(anyseq.Head(inSeq, 3), anyseq.Slice(inSeq, 0, 3), prec) {
+			t.Error("Head should match Slice")
+		}
+
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.Slice(inSeq, 1, 4)
+			},
+			V: varList,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestSeqConcat(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		reversed := anyseq.Reverse(anyseq.Slice(inSeq, 1, 3))
+		actual := anyseq.Concat(inSeq, reversed)
+		var expected [][]anyvec.Vector
+		seqs1 := anyseq.SeparateSeqs(inSeq.Output())
+		seqs2 := anyseq.SeparateSeqs(reversed.Output())
+		for i, seq := range seqs1 {
+			joined := append(append([]anyvec.Vector{}, seq...), seqs2[i]...)
+			expected = append(expected, joined)
+		}
+		if !SeqsClose(actual, anyseq.ConstSeqList(c, expected), prec) {
+			t.Error("unexpected output")
+		}
+
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.Concat(anyseq.Reverse(anyseq.Slice(inSeq, 1, 3)), inSeq)
+			},
+			V: varList,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestSeqDownsample(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		actual := anyseq.Downsample(inSeq, 2)
+		var expected [][]anyvec.Vector
+		for _, seq := range anyseq.SeparateSeqs(inSeq.Output()) {
+			var kept []anyvec.Vector
+			for i := 0; i < len(seq); i += 2 {
+				kept = append(kept, seq[i])
+			}
+			expected = append(expected, kept)
+		}
+		if !SeqsClose(actual, anyseq.ConstSeqList(c, expected), prec) {
+			t.Error("unexpected output")
+		}
+
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.Downsample(inSeq, 3)
+			},
+			V: varList,
+		}
+		ch.FullCheck(t)
+	})
+}
+
+func TestSeqWindow(t *testing.T) {
+	runWithCreators(t, func(t *testing.T, c anyvec.Creator, prec float64) {
+		inSeq, varList := makeBasicTestSeqs(c)
+		for _, params := range [][2]int{{2, 2}, {3, 1}, {2, 3}} {
+			size, stride := params[0], params[1]
+			actual := anyseq.Window(inSeq, size, stride)
+			var expected [][]anyvec.Vector
+			for _, seq := range anyseq.SeparateSeqs(inSeq.Output()) {
+				var windows []anyvec.Vector
+				for i := 0; i+size <= len(seq); i += stride {
+					windows = append(windows, c.Concat(seq[i:i+size]...))
+				}
+				expected = append(expected, windows)
+			}
+			if !SeqsClose(actual, anyseq.ConstSeqList(c, expected), prec) {
+				t.Errorf("unexpected output for size %d stride %d", size, stride)
+			}
+		}
+
+		ch := &SeqChecker{
+			F: func() anyseq.Seq {
+				return anyseq.Window(inSeq, 3, 1)
+			},
+			V: varList,
+		}
+		ch.FullCheck(t)
+	})
+}
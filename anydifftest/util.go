@@ -104,6 +104,10 @@ func setComponent(v anyvec.Vector, i int, val float64) {
 	v.SetData(data)
 }
 
+func makeVecData(c anyvec.Creator, data ...float64) anyvec.Vector {
+	return c.MakeVectorData(c.MakeNumericList(data))
+}
+
 func makeRandomVec(c anyvec.Creator, size int) *anydiff.Var {
 	v := c.MakeVector(size)
 	anyvec.Rand(v, anyvec.Normal, nil)
@@ -0,0 +1,52 @@
+package anyfwd
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// HVP computes the product of the Hessian of a scalar
+// function f with a direction vector.
+//
+// This works by back-propagating through a graph of dual
+// vectors (forward-over-reverse), so it costs roughly as
+// much as a few gradient computations.
+//
+// The vars are temporarily converted to use forward
+// auto-diff while f is called, and the direction for each
+// var is given by the corresponding entry of direction.
+// A nil direction is treated as zero.
+// Every Var used by f must be in vars.
+// Like with JVP, the vars must not be used concurrently
+// by other goroutines while HVP runs.
+//
+// The result maps each var to its part of the product.
+func HVP(f func() anydiff.Res, vars []*anydiff.Var,
+	direction []anyvec.Vector) anydiff.Grad {
+	if len(direction) != len(vars) {
+		panic("direction count mismatch")
+	}
+	c := &Creator{ValueCreator: varsCreator(vars), GradSize: 1}
+	res := anydiff.Grad{}
+	withDuals(c, vars, func(i int, v *anydiff.Var) []anyvec.Vector {
+		if direction[i] == nil {
+			return []anyvec.Vector{c.ValueCreator.MakeVector(v.Vector.Len())}
+		}
+		if direction[i].Len() != v.Vector.Len() {
+			panic("direction size mismatch")
+		}
+		return []anyvec.Vector{direction[i].Copy()}
+	}, func() {
+		out := f()
+		if out.Output().Len() != 1 {
+			panic("function must have a scalar output")
+		}
+		upstream := c.MakeVector(1)
+		upstream.AddScalar(c.MakeNumeric(1))
+		grad := anydiff.VJP(out, upstream, vars...)
+		for _, v := range vars {
+			res[v] = grad[v].(*Vector).Jacobian[0]
+		}
+	})
+	return res
+}
@@ -0,0 +1,67 @@
+package anyfwd
+
+import (
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+func TestHVP(t *testing.T) {
+	v1, v2 := jacobianTestVars()
+	vars := []*anydiff.Var{v1, v2}
+	f := func() anydiff.Res {
+		prod := anydiff.Mul(anydiff.Tanh(v1), anydiff.Sin(v2))
+		return anydiff.Sum(anydiff.Add(prod, anydiff.Mul(v1, anydiff.Exp(v1))))
+	}
+	direction := []anyvec.Vector{randomTestVec(3), randomTestVec(3)}
+
+	actual := HVP(f, vars, direction)
+	if _, ok := v1.Vector.(*Vector); ok {
+		t.Fatal("variable was not restored")
+	}
+
+	// Finite differences of the gradient along direction.
+	gradient := func(scale float64) anydiff.Grad {
+		for i, v := range vars {
+			d := direction[i].Copy()
+			d.Scale(d.Creator().MakeNumeric(scale))
+			v.Vector.Add(d)
+		}
+		g := anydiff.NewGrad(vars...)
+		one := v1.Vector.Creator().MakeVector(1)
+		one.AddScalar(one.Creator().MakeNumeric(1))
+		f().Propagate(one, g)
+		for i, v := range vars {
+			d := direction[i].Copy()
+			d.Scale(d.Creator().MakeNumeric(-scale))
+			v.Vector.Add(d)
+		}
+		return g
+	}
+	g1 := gradient(diffDelta)
+	g2 := gradient(-diffDelta)
+	for _, v := range vars {
+		expected := g1[v].Copy()
+		expected.Sub(g2[v])
+		expected.Scale(expected.Creator().MakeNumeric(0.5 / diffDelta))
+		e := expected.Data().([]float64)
+		a := actual[v].Data().([]float64)
+		if !slicesClose(a, e) {
+			t.Errorf("expected %v but got %v", e, a)
+		}
+	}
+}
+
+func TestHVPZeroDirection(t *testing.T) {
+	v1, v2 := jacobianTestVars()
+	f := func() anydiff.Res {
+		return anydiff.Sum(anydiff.Mul(anydiff.Tanh(v1), v2))
+	}
+	actual := HVP(f, []*anydiff.Var{v1, v2}, []anyvec.Vector{nil, randomTestVec(3)})
+	// The Hessian block for v2 is zero, so the product only
+	// depends on the mixed terms.
+	if a := actual[v2].Data().([]float64); !slicesClose(a, []float64{0, 0, 0}) {
+		t.Errorf("expected zero but got %v", a)
+	}
+}
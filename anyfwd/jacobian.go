@@ -0,0 +1,172 @@
+package anyfwd
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// JVP computes the product of the Jacobian of f with a
+// tangent vector, using forward auto-diff.
+//
+// The vars are temporarily converted to use forward
+// auto-diff while f is called, and their tangents are
+// specified by the corresponding entries of tangents.
+// A nil tangent is treated as zero.
+// Every Var used by f must be in vars.
+// If the output of f does not depend on the vars, the
+// Jacobian-vector product is zero.
+//
+// Since the vars' vectors are replaced while f runs, the
+// vars must not be used concurrently by other goroutines.
+// The original vectors are restored before JVP returns.
+//
+// The output of f and the Jacobian-vector product are
+// returned as vectors from the original Creator.
+func JVP(f func() anydiff.Res, vars []*anydiff.Var,
+	tangents []anyvec.Vector) (out, jvp anyvec.Vector) {
+	if len(tangents) != len(vars) {
+		panic("tangent count mismatch")
+	}
+	c := &Creator{ValueCreator: varsCreator(vars), GradSize: 1}
+	var res *Vector
+	withDuals(c, vars, func(i int, v *anydiff.Var) []anyvec.Vector {
+		if tangents[i] == nil {
+			return []anyvec.Vector{c.ValueCreator.MakeVector(v.Vector.Len())}
+		}
+		if tangents[i].Len() != v.Vector.Len() {
+			panic("tangent size mismatch")
+		}
+		return []anyvec.Vector{tangents[i].Copy()}
+	}, func() {
+		res = dualOutput(c, f().Output())
+	})
+	return res.Values, res.Jacobian[0]
+}
+
+// Jacobian computes the full Jacobian of f with respect
+// to the vars.
+//
+// The Jacobian has one row per output component and one
+// column per input component, where the columns are
+// ordered by var and then by component.
+//
+// If there are fewer inputs than outputs, forward
+// auto-diff is used, in which case every Var used by f
+// must be in vars, and the vars are temporarily modified
+// as described for JVP.
+// Otherwise, reverse auto-diff is used.
+func Jacobian(f func() anydiff.Res, vars []*anydiff.Var) *anyvec.Matrix {
+	c := varsCreator(vars)
+	var numIn int
+	for _, v := range vars {
+		numIn += v.Vector.Len()
+	}
+	r := f()
+	numOut := r.Output().Len()
+
+	var data anyvec.Vector
+	if numIn > 0 && numIn < numOut {
+		data = forwardJacobian(f, vars, numIn, numOut)
+	} else {
+		data = reverseJacobian(r, vars, numOut)
+	}
+	if data == nil {
+		data = c.MakeVector(0)
+	}
+	return &anyvec.Matrix{Data: data, Rows: numOut, Cols: numIn}
+}
+
+func forwardJacobian(f func() anydiff.Res, vars []*anydiff.Var,
+	numIn, numOut int) anyvec.Vector {
+	c := &Creator{ValueCreator: varsCreator(vars), GradSize: numIn}
+	var offset int
+	var res *Vector
+	withDuals(c, vars, func(i int, v *anydiff.Var) []anyvec.Vector {
+		var jacobian []anyvec.Vector
+		for j := 0; j < numIn; j++ {
+			jacobian = append(jacobian, oneHot(c.ValueCreator, v.Vector.Len(), j-offset))
+		}
+		offset += v.Vector.Len()
+		return jacobian
+	}, func() {
+		res = dualOutput(c, f().Output())
+	})
+
+	// Each Jacobian entry is a column of the result.
+	cols := c.ValueCreator.Concat(res.Jacobian...)
+	rows := cols.Creator().MakeVector(cols.Len())
+	anyvec.Transpose(cols, rows, numIn)
+	return rows
+}
+
+func reverseJacobian(r anydiff.Res, vars []*anydiff.Var, numOut int) anyvec.Vector {
+	c := r.Output().Creator()
+	var rows []anyvec.Vector
+	for i := 0; i < numOut; i++ {
+		g := anydiff.VJP(r, oneHot(c, numOut, i), vars...)
+		for _, v := range vars {
+			rows = append(rows, g[v])
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return c.Concat(rows...)
+}
+
+// withDuals temporarily converts the vars to dual vectors
+// with the given Jacobians while f is called.
+//
+// This modifies the vars in place, since f refers to them
+// directly.
+func withDuals(c *Creator, vars []*anydiff.Var,
+	jacobian func(i int, v *anydiff.Var) []anyvec.Vector,
+	f func()) {
+	oldVecs := make([]anyvec.Vector, len(vars))
+	for i, v := range vars {
+		oldVecs[i] = v.Vector
+	}
+	defer func() {
+		for i, v := range vars {
+			v.Vector = oldVecs[i]
+		}
+	}()
+	for i, v := range vars {
+		v.Vector = &Vector{
+			CreatorPtr: c,
+			Values:     oldVecs[i].Copy(),
+			Jacobian:   jacobian(i, v),
+		}
+	}
+	f()
+}
+
+// dualOutput converts the output of a function to a dual
+// vector.
+// Outputs which do not depend on any dual vectors have
+// zero Jacobians.
+func dualOutput(c *Creator, out anyvec.Vector) *Vector {
+	if v, ok := out.(*Vector); ok {
+		return v
+	}
+	jacobian := make([]anyvec.Vector, c.GradSize)
+	for i := range jacobian {
+		jacobian[i] = c.ValueCreator.MakeVector(out.Len())
+	}
+	return &Vector{CreatorPtr: c, Values: out.Copy(), Jacobian: jacobian}
+}
+
+func varsCreator(vars []*anydiff.Var) anyvec.Creator {
+	if len(vars) == 0 {
+		panic("no variables")
+	}
+	return vars[0].Vector.Creator()
+}
+
+func oneHot(c anyvec.Creator, size, idx int) anyvec.Vector {
+	data := make([]float64, size)
+	if idx >= 0 && idx < size {
+		data[idx] = 1
+	}
+	return c.MakeVectorData(c.MakeNumericList(data))
+}
@@ -0,0 +1,145 @@
+package anyfwd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec64"
+)
+
+func TestJVP(t *testing.T) {
+	v1, v2 := jacobianTestVars()
+	f := jacobianTestFunc(v1, v2, false)
+	tangents := []anyvec.Vector{randomTestVec(3), nil}
+	tangentData := append(tangents[0].Data().([]float64), 0, 0, 0)
+
+	out, jvp := JVP(f, []*anydiff.Var{v1, v2}, tangents)
+	if _, ok := v1.Vector.(*Vector); ok {
+		t.Fatal("variable was not restored")
+	}
+	expectedOut := f().Output().Data().([]float64)
+	if !slicesClose(out.Data().([]float64), expectedOut) {
+		t.Errorf("expected output %v but got %v", expectedOut, out.Data())
+	}
+
+	jacobian := approxJacobian(f, []*anydiff.Var{v1, v2})
+	expected := make([]float64, len(jacobian))
+	for i, row := range jacobian {
+		for j, x := range row {
+			expected[i] += x * tangentData[j]
+		}
+	}
+	if actual := jvp.Data().([]float64); !slicesClose(actual, expected) {
+		t.Errorf("expected JVP %v but got %v", expected, actual)
+	}
+}
+
+func TestJacobian(t *testing.T) {
+	for _, wide := range []bool{false, true} {
+		v1, v2 := jacobianTestVars()
+		vars := []*anydiff.Var{v1, v2}
+		f := jacobianTestFunc(v1, v2, wide)
+		expected := approxJacobian(f, vars)
+
+		actual := Jacobian(f, vars)
+		if actual.Rows != len(expected) || actual.Cols != 6 {
+			t.Errorf("wide=%v: bad shape %dx%d", wide, actual.Rows, actual.Cols)
+			continue
+		}
+		data := actual.Data.Data().([]float64)
+		for i, row := range expected {
+			if !slicesClose(data[i*6:(i+1)*6], row) {
+				t.Errorf("wide=%v: row %d should be %v but got %v", wide, i, row,
+					data[i*6:(i+1)*6])
+			}
+		}
+	}
+}
+
+func TestJVPConstOutput(t *testing.T) {
+	v1, v2 := jacobianTestVars()
+	constVec := randomTestVec(4)
+	f := func() anydiff.Res {
+		return anydiff.NewConst(constVec)
+	}
+	tangents := []anyvec.Vector{randomTestVec(3), randomTestVec(3)}
+	out, jvp := JVP(f, []*anydiff.Var{v1, v2}, tangents)
+	if !slicesClose(out.Data().([]float64), constVec.Data().([]float64)) {
+		t.Errorf("expected output %v but got %v", constVec.Data(), out.Data())
+	}
+	if !slicesClose(jvp.Data().([]float64), make([]float64, 4)) {
+		t.Errorf("expected zero JVP but got %v", jvp.Data())
+	}
+
+	jacobian := Jacobian(f, []*anydiff.Var{v1})
+	if jacobian.Rows != 4 || jacobian.Cols != 3 {
+		t.Fatalf("bad shape %dx%d", jacobian.Rows, jacobian.Cols)
+	}
+	if !slicesClose(jacobian.Data.Data().([]float64), make([]float64, 12)) {
+		t.Errorf("expected zero Jacobian but got %v", jacobian.Data.Data())
+	}
+}
+
+func jacobianTestVars() (*anydiff.Var, *anydiff.Var) {
+	return anydiff.NewVar(randomTestVec(3)), anydiff.NewVar(randomTestVec(3))
+}
+
+// jacobianTestFunc creates a function with more outputs
+// than inputs if wide is true, or fewer otherwise.
+func jacobianTestFunc(v1, v2 *anydiff.Var, wide bool) func() anydiff.Res {
+	return func() anydiff.Res {
+		prod := anydiff.Mul(anydiff.Tanh(v1), v2)
+		if !wide {
+			return anydiff.Add(prod, anydiff.Sin(v2))
+		}
+		return anydiff.Concat(prod, anydiff.Sin(v1), anydiff.Exp(v2))
+	}
+}
+
+func approxJacobian(f func() anydiff.Res, vars []*anydiff.Var) [][]float64 {
+	var cols [][]float64
+	for _, v := range vars {
+		for i := 0; i < v.Vector.Len(); i++ {
+			orig := v.Vector.Copy()
+			data := orig.Data().([]float64)
+			data[i] += diffDelta
+			v.Vector.SetData(data)
+			out1 := f().Output().Data().([]float64)
+			data[i] -= 2 * diffDelta
+			v.Vector.SetData(data)
+			out2 := f().Output().Data().([]float64)
+			v.Vector.Set(orig)
+			for j := range out1 {
+				out1[j] = (out1[j] - out2[j]) / (2 * diffDelta)
+			}
+			cols = append(cols, out1)
+		}
+	}
+	rows := make([][]float64, len(cols[0]))
+	for i := range rows {
+		for _, col := range cols {
+			rows[i] = append(rows[i], col[i])
+		}
+	}
+	return rows
+}
+
+func randomTestVec(size int) anyvec.Vector {
+	res := anyvec64.DefaultCreator{}.MakeVector(size)
+	anyvec.Rand(res, anyvec.Normal, nil)
+	return res
+}
+
+func slicesClose(actual, expected []float64) bool {
+	if len(actual) != len(expected) {
+		return false
+	}
+	for i, x := range expected {
+		if math.Abs(x-actual[i]) > diffEpsilon {
+			return false
+		}
+	}
+	return true
+}
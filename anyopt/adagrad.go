@@ -0,0 +1,51 @@
+package anyopt
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// DefaultAdagradEpsilon is the default epsilon for
+// Adagrad.
+const DefaultAdagradEpsilon = 1e-8
+
+// Adagrad implements the Adagrad optimizer, which divides
+// the gradient by the root of the sum of all the previous
+// squared gradients.
+type Adagrad struct {
+	Rate Schedule
+
+	// Epsilon is used to avoid division by zero.
+	// If it is 0, a default is used.
+	Epsilon float64
+
+	// WeightDecay is the L2 regularization coefficient.
+	WeightDecay float64
+
+	step       int
+	sumSquares anydiff.Grad
+}
+
+// Step performs a step of Adagrad.
+func (a *Adagrad) Step(g anydiff.Grad) {
+	applyWeightDecay(g, a.WeightDecay)
+	if a.sumSquares == nil {
+		a.sumSquares = anydiff.Grad{}
+	}
+
+	epsilon := valueOrDefault(a.Epsilon, DefaultAdagradEpsilon)
+
+	rate := a.Rate.Rate(a.step)
+	a.step++
+
+	for v, grad := range g {
+		c := grad.Creator()
+		states := []anyvec.Vector{stateVec(a.sumSquares, v)}
+		updateVar(v, grad, states, func(grad anyvec.Vector, states []anyvec.Vector) {
+			sumSquare := states[0]
+			addSquared(sumSquare, grad, 1)
+			grad.Div(rootDenominator(sumSquare, 1, epsilon))
+			grad.Scale(c.MakeNumeric(-rate))
+		})
+	}
+}
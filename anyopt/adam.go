@@ -0,0 +1,79 @@
+package anyopt
+
+import (
+	"math"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// Default hyper-parameters for Adam.
+const (
+	DefaultAdamDecay1  = 0.9
+	DefaultAdamDecay2  = 0.999
+	DefaultAdamEpsilon = 1e-8
+)
+
+// Adam implements the Adam optimizer.
+//
+// See https://arxiv.org/abs/1412.6980.
+type Adam struct {
+	Rate Schedule
+
+	// Decay rates for the first and second moments.
+	// If these are 0, defaults are used.
+	Decay1 float64
+	Decay2 float64
+
+	// Epsilon is used to avoid division by zero.
+	// If it is 0, a default is used.
+	Epsilon float64
+
+	// WeightDecay is the L2 regularization coefficient.
+	WeightDecay float64
+
+	step         int
+	firstMoment  anydiff.Grad
+	secondMoment anydiff.Grad
+}
+
+// Step performs a step of Adam.
+func (a *Adam) Step(g anydiff.Grad) {
+	applyWeightDecay(g, a.WeightDecay)
+	if a.firstMoment == nil {
+		a.firstMoment = anydiff.Grad{}
+		a.secondMoment = anydiff.Grad{}
+	}
+
+	decay1 := valueOrDefault(a.Decay1, DefaultAdamDecay1)
+	decay2 := valueOrDefault(a.Decay2, DefaultAdamDecay2)
+	epsilon := valueOrDefault(a.Epsilon, DefaultAdamEpsilon)
+
+	rate := a.Rate.Rate(a.step)
+	a.step++
+	correction1 := 1 - math.Pow(decay1, float64(a.step))
+	correction2 := 1 - math.Pow(decay2, float64(a.step))
+
+	for v, grad := range g {
+		c := grad.Creator()
+		states := []anyvec.Vector{
+			stateVec(a.firstMoment, v),
+			stateVec(a.secondMoment, v),
+		}
+		updateVar(v, grad, states, func(grad anyvec.Vector, states []anyvec.Vector) {
+			first := states[0]
+			first.Scale(c.MakeNumeric(decay1))
+			scaledGrad := grad.Copy()
+			scaledGrad.Scale(c.MakeNumeric(1 - decay1))
+			first.Add(scaledGrad)
+
+			second := states[1]
+			second.Scale(c.MakeNumeric(decay2))
+			addSquared(second, grad, 1-decay2)
+
+			grad.Set(first)
+			grad.Div(rootDenominator(second, 1/correction2, epsilon))
+			grad.Scale(c.MakeNumeric(-rate / correction1))
+		})
+	}
+}
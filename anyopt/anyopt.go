@@ -0,0 +1,116 @@
+// Package anyopt implements gradient-based optimizers
+// which consume anydiff.Grad values.
+//
+// The optimizers in this package work with any
+// anyvec.Creator, including anyfwd.Creator.
+// They also support sparse *anydiff.RowGrad gradients,
+// in which case only the touched rows are updated.
+package anyopt
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// An Optimizer updates variables using gradients.
+type Optimizer interface {
+	// Step updates the variables in g in order to decrease
+	// the objective whose gradient is g.
+	//
+	// Any per-variable state is keyed by the *anydiff.Var
+	// pointers in g, just like in an anydiff.Grad.
+	//
+	// Step may modify the gradient as it likes.
+	Step(g anydiff.Grad)
+}
+
+// applyWeightDecay adds decay*v to the gradient of every
+// variable v in g, thus implementing L2 regularization.
+func applyWeightDecay(g anydiff.Grad, decay float64) {
+	if decay == 0 {
+		return
+	}
+	for v, grad := range g {
+		var scaled anyvec.Vector
+		if rg, ok := grad.(*anydiff.RowGrad); ok {
+			scaled = rg.Gather(v.Vector)
+			grad = rg.Values
+		} else {
+			scaled = v.Vector.Copy()
+		}
+		scaled.Scale(scaled.Creator().MakeNumeric(decay))
+		grad.Add(scaled)
+	}
+}
+
+// updateVar adds a step to a variable.
+//
+// The update function turns a gradient into the step,
+// updating the variable's state vectors in place.
+//
+// If grad is a *anydiff.RowGrad, then the update only sees
+// the touched rows of the gradient and the state vectors,
+// and only those rows of the variable and state vectors
+// are changed.
+// This makes sparse updates "lazy": the state for an
+// untouched row is not decayed until the row is touched.
+func updateVar(v *anydiff.Var, grad anyvec.Vector, states []anyvec.Vector,
+	update func(grad anyvec.Vector, states []anyvec.Vector)) {
+	rg, ok := grad.(*anydiff.RowGrad)
+	if !ok {
+		update(grad, states)
+		v.Vector.Add(grad)
+		return
+	}
+	rows := make([]anyvec.Vector, len(states))
+	oldRows := make([]anyvec.Vector, len(states))
+	for i, state := range states {
+		rows[i] = rg.Gather(state)
+		oldRows[i] = rows[i].Copy()
+	}
+	step := rg.Values.Copy()
+	update(step, rows)
+	for i, state := range states {
+		rows[i].Sub(oldRows[i])
+		rg.Scatter(rows[i], state)
+	}
+	rg.Scatter(step, v.Vector)
+}
+
+// stateVec gets the state vector for a variable, creating
+// a zero vector if necessary.
+func stateVec(state anydiff.Grad, v *anydiff.Var) anyvec.Vector {
+	if vec, ok := state[v]; ok {
+		return vec
+	}
+	vec := v.Vector.Creator().MakeVector(v.Vector.Len())
+	state[v] = vec
+	return vec
+}
+
+// addSquared adds scale*grad^2 to v.
+func addSquared(v, grad anyvec.Vector, scale float64) {
+	sq := grad.Copy()
+	sq.Mul(grad)
+	sq.Scale(sq.Creator().MakeNumeric(scale))
+	v.Add(sq)
+}
+
+// rootDenominator computes sqrt(scale*v) + epsilon.
+func rootDenominator(v anyvec.Vector, scale, epsilon float64) anyvec.Vector {
+	c := v.Creator()
+	res := v.Copy()
+	if scale != 1 {
+		res.Scale(c.MakeNumeric(scale))
+	}
+	anyvec.Pow(res, c.MakeNumeric(0.5))
+	res.AddScalar(c.MakeNumeric(epsilon))
+	return res
+}
+
+func valueOrDefault(x, def float64) float64 {
+	if x == 0 {
+		return def
+	}
+	return x
+}
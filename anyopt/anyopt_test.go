@@ -0,0 +1,229 @@
+package anyopt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anydiff/anyfwd"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/anyvec/anyvec64"
+)
+
+func TestOptimizers(t *testing.T) {
+	optimizers := map[string]func() Optimizer{
+		"SGD": func() Optimizer {
+			return &SGD{Rate: ConstRate(0.1)}
+		},
+		"Momentum": func() Optimizer {
+			return &SGD{Rate: ConstRate(0.05), Momentum: 0.9}
+		},
+		"Nesterov": func() Optimizer {
+			return &SGD{Rate: ConstRate(0.05), Momentum: 0.9, Nesterov: true}
+		},
+		"Adam": func() Optimizer {
+			return &Adam{Rate: ConstRate(0.1)}
+		},
+		"RMSProp": func() Optimizer {
+			return &RMSProp{Rate: &ExpDecay{Init: 0.1, Decay: 0.99}}
+		},
+		"Adagrad": func() Optimizer {
+			return &Adagrad{Rate: ConstRate(0.5)}
+		},
+	}
+	creators := map[string]anyvec.Creator{
+		"float32": anyvec32.DefaultCreator{},
+		"float64": anyvec64.DefaultCreator{},
+		"anyfwd": &anyfwd.Creator{
+			ValueCreator: anyvec64.DefaultCreator{},
+			GradSize:     2,
+		},
+	}
+	for optName, makeOpt := range optimizers {
+		for cName, c := range creators {
+			t.Run(optName+"/"+cName, func(t *testing.T) {
+				testQuadratic(t, c, makeOpt())
+			})
+		}
+	}
+}
+
+func TestWeightDecay(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	v := anydiff.NewVar(c.MakeVectorData([]float64{2, -4}))
+	opt := &SGD{Rate: ConstRate(0.25), WeightDecay: 0.5}
+	opt.Step(anydiff.NewGrad(v))
+	actual := v.Vector.Data().([]float64)
+	expected := []float64{1.75, -3.5}
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("expected %v but got %v", expected, actual)
+			break
+		}
+	}
+}
+
+func TestAdamFirstStep(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	v := anydiff.NewVar(c.MakeVectorData([]float64{1, 2, 3}))
+	grad := anydiff.Grad{v: c.MakeVectorData([]float64{-0.001, 3, 1000})}
+	opt := &Adam{Rate: ConstRate(0.01)}
+	opt.Step(grad)
+	actual := v.Vector.Data().([]float64)
+	expected := []float64{1.01, 1.99, 2.99}
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-4 {
+			t.Errorf("expected %v but got %v", expected, actual)
+			break
+		}
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	schedules := []Schedule{
+		ConstRate(0.5),
+		ScheduleFunc(func(step int) float64 {
+			return 1 / float64(step+1)
+		}),
+		&ExpDecay{Init: 2, Decay: 0.5},
+		&StepDecay{Init: 1, Decay: 0.1, Interval: 2},
+		&StepDecay{Init: 1, Decay: 0.5},
+	}
+	expected := [][]float64{
+		{0.5, 0.5, 0.5, 0.5},
+		{1, 0.5, 1.0 / 3, 0.25},
+		{2, 1, 0.5, 0.25},
+		{1, 1, 0.1, 0.1},
+		{1, 0.5, 0.25, 0.125},
+	}
+	for i, s := range schedules {
+		for step, x := range expected[i] {
+			if a := s.Rate(step); math.Abs(a-x) > 1e-8 {
+				t.Errorf("schedule %d step %d: expected %f but got %f", i, step, x, a)
+			}
+		}
+	}
+}
+
+func testQuadratic(t *testing.T, c anyvec.Creator, opt Optimizer) {
+	target := anydiff.NewConst(c.MakeVectorData(c.MakeNumericList([]float64{1, -2, 0.5})))
+	v1 := anydiff.NewVar(c.MakeVectorData(c.MakeNumericList([]float64{3, 1, -1})))
+	v2 := anydiff.NewVar(c.MakeVectorData(c.MakeNumericList([]float64{-1})))
+	cost := func() anydiff.Res {
+		diff := anydiff.Sub(v1, target)
+		return anydiff.Add(anydiff.Dot(diff, diff), anydiff.Square(v2))
+	}
+	for i := 0; i < 300; i++ {
+		g := anydiff.NewGrad(v1, v2)
+		out := cost()
+		out.Propagate(c.MakeVectorData(c.MakeNumericList([]float64{1})), g)
+		opt.Step(g)
+	}
+	final := c.Float64Slice(cost().Output().Data())[0]
+	if final > 1e-2 {
+		t.Errorf("final cost too high: %f", final)
+	}
+}
+
+func TestSparseOptimizers(t *testing.T) {
+	optimizers := map[string]func() Optimizer{
+		"SGD": func() Optimizer {
+			return &SGD{Rate: ConstRate(0.1)}
+		},
+		"Nesterov": func() Optimizer {
+			return &SGD{Rate: ConstRate(0.05), Momentum: 0.9, Nesterov: true}
+		},
+		"Adam": func() Optimizer {
+			return &Adam{Rate: ConstRate(0.1)}
+		},
+		"RMSProp": func() Optimizer {
+			return &RMSProp{Rate: ConstRate(0.1)}
+		},
+		"Adagrad": func() Optimizer {
+			return &Adagrad{Rate: ConstRate(0.5)}
+		},
+	}
+	c := anyvec64.DefaultCreator{}
+	for name, makeOpt := range optimizers {
+		t.Run(name, func(t *testing.T) {
+			denseVar := anydiff.NewVar(c.MakeVectorData([]float64{1, 2, 3, 4, 5, 6, 7, 8}))
+			sparseVar := anydiff.NewVar(denseVar.Vector.Copy())
+			denseOpt := makeOpt()
+			sparseOpt := makeOpt()
+
+			// Every step touches the same rows, so lazy sparse
+			// updates should match dense updates exactly.
+			for i := 0; i < 3; i++ {
+				ids := []int{3, 1, 3}
+				upstream := c.MakeVectorData([]float64{1, -2, 0.5, float64(i), -1, 3})
+
+				dense := anydiff.NewGrad(denseVar)
+				anydiff.Embedding(denseVar, 2, ids).Propagate(upstream.Copy(), dense)
+				denseOpt.Step(dense)
+
+				sparse := anydiff.Grad{sparseVar: anydiff.NewRowGrad(sparseVar, 2)}
+				anydiff.Embedding(sparseVar, 2, ids).Propagate(upstream.Copy(), sparse)
+				sparseOpt.Step(sparse)
+			}
+
+			expected := denseVar.Vector.Data().([]float64)
+			actual := sparseVar.Vector.Data().([]float64)
+			for i, x := range expected {
+				if math.Abs(actual[i]-x) > 1e-8 {
+					t.Errorf("expected %v but got %v", expected, actual)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestSparseClear(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	v := anydiff.NewVar(c.MakeVectorData([]float64{1, 2, 3, 4, 5, 6}))
+	grad := anydiff.NewRowGrad(v, 2)
+	g := anydiff.Grad{v: grad}
+	opt := &Adam{Rate: ConstRate(0.1)}
+
+	anydiff.Embedding(v, 2, []int{1}).Propagate(c.MakeVectorData([]float64{1, -1}), g)
+	opt.Step(g)
+	afterFirst := v.Vector.Data().([]float64)
+
+	// After clearing, the second step should only update
+	// the row that it touches, even though Adam still has
+	// momentum for the first row.
+	g.Clear()
+	if len(grad.Indices) != 0 || grad.Values.Len() != 0 {
+		t.Fatal("clearing should remove every row")
+	}
+	anydiff.Embedding(v, 2, []int{2}).Propagate(c.MakeVectorData([]float64{1, -1}), g)
+	opt.Step(g)
+	actual := v.Vector.Data().([]float64)
+	for i := 0; i < 4; i++ {
+		if actual[i] != afterFirst[i] {
+			t.Errorf("untouched row changed: expected %v but got %v", afterFirst[:4], actual[:4])
+			break
+		}
+	}
+	if actual[4] == afterFirst[4] || actual[5] == afterFirst[5] {
+		t.Error("touched row should be updated")
+	}
+}
+
+func TestSparseWeightDecay(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	v := anydiff.NewVar(c.MakeVectorData([]float64{2, -4, 6, 8}))
+	grad := anydiff.NewRowGrad(v, 2)
+	grad.AddRows([]int{1}, c.MakeVectorData([]float64{0, 0}))
+	opt := &SGD{Rate: ConstRate(0.25), WeightDecay: 0.5}
+	opt.Step(anydiff.Grad{v: grad})
+	actual := v.Vector.Data().([]float64)
+	expected := []float64{2, -4, 5.25, 7}
+	for i, x := range expected {
+		if math.Abs(actual[i]-x) > 1e-8 {
+			t.Errorf("expected %v but got %v", expected, actual)
+			break
+		}
+	}
+}
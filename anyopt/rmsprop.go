@@ -0,0 +1,58 @@
+package anyopt
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// Default hyper-parameters for RMSProp.
+const (
+	DefaultRMSPropDecay   = 0.9
+	DefaultRMSPropEpsilon = 1e-8
+)
+
+// RMSProp implements the RMSProp optimizer, which divides
+// the gradient by a running average of its magnitude.
+type RMSProp struct {
+	Rate Schedule
+
+	// Decay is the decay rate for the running average.
+	// If it is 0, a default is used.
+	Decay float64
+
+	// Epsilon is used to avoid division by zero.
+	// If it is 0, a default is used.
+	Epsilon float64
+
+	// WeightDecay is the L2 regularization coefficient.
+	WeightDecay float64
+
+	step        int
+	meanSquares anydiff.Grad
+}
+
+// Step performs a step of RMSProp.
+func (r *RMSProp) Step(g anydiff.Grad) {
+	applyWeightDecay(g, r.WeightDecay)
+	if r.meanSquares == nil {
+		r.meanSquares = anydiff.Grad{}
+	}
+
+	decay := valueOrDefault(r.Decay, DefaultRMSPropDecay)
+	epsilon := valueOrDefault(r.Epsilon, DefaultRMSPropEpsilon)
+
+	rate := r.Rate.Rate(r.step)
+	r.step++
+
+	for v, grad := range g {
+		c := grad.Creator()
+		states := []anyvec.Vector{stateVec(r.meanSquares, v)}
+		updateVar(v, grad, states, func(grad anyvec.Vector, states []anyvec.Vector) {
+			meanSquare := states[0]
+			meanSquare.Scale(c.MakeNumeric(decay))
+			addSquared(meanSquare, grad, 1-decay)
+			grad.Div(rootDenominator(meanSquare, 1, epsilon))
+			grad.Scale(c.MakeNumeric(-rate))
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package anyopt
+
+import "math"
+
+// A Schedule determines the learning rate for each step
+// of an optimizer.
+//
+// Steps are numbered starting at 0.
+type Schedule interface {
+	Rate(step int) float64
+}
+
+// ConstRate is a Schedule with a fixed learning rate.
+type ConstRate float64
+
+// Rate returns c.
+func (c ConstRate) Rate(step int) float64 {
+	return float64(c)
+}
+
+// ScheduleFunc is a Schedule which calls a function.
+type ScheduleFunc func(step int) float64
+
+// Rate calls s.
+func (s ScheduleFunc) Rate(step int) float64 {
+	return s(step)
+}
+
+// ExpDecay is a Schedule which multiplies the learning
+// rate by Decay after every step.
+type ExpDecay struct {
+	Init  float64
+	Decay float64
+}
+
+// Rate computes Init*Decay^step.
+func (e *ExpDecay) Rate(step int) float64 {
+	return e.Init * math.Pow(e.Decay, float64(step))
+}
+
+// StepDecay is a Schedule which multiplies the learning
+// rate by Decay once every Interval steps.
+type StepDecay struct {
+	Init  float64
+	Decay float64
+
+	// Interval is the number of steps between decays.
+	// If it is 0, the rate decays after every step.
+	Interval int
+}
+
+// Rate computes Init*Decay^floor(step/Interval).
+func (s *StepDecay) Rate(step int) float64 {
+	interval := s.Interval
+	if interval < 0 {
+		panic("step decay interval must not be negative")
+	} else if interval == 0 {
+		interval = 1
+	}
+	return s.Init * math.Pow(s.Decay, float64(step/interval))
+}
@@ -0,0 +1,58 @@
+package anyopt
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// SGD implements stochastic gradient descent with
+// optional momentum.
+type SGD struct {
+	Rate Schedule
+
+	// Momentum is the decay rate for the velocity.
+	// If it is 0, no momentum is used.
+	Momentum float64
+
+	// Nesterov indicates whether or not to use Nesterov
+	// momentum.
+	Nesterov bool
+
+	// WeightDecay is the L2 regularization coefficient.
+	WeightDecay float64
+
+	step     int
+	velocity anydiff.Grad
+}
+
+// Step performs a step of gradient descent.
+func (s *SGD) Step(g anydiff.Grad) {
+	applyWeightDecay(g, s.WeightDecay)
+	if s.velocity == nil {
+		s.velocity = anydiff.Grad{}
+	}
+	rate := s.Rate.Rate(s.step)
+	s.step++
+	for v, grad := range g {
+		c := grad.Creator()
+		var states []anyvec.Vector
+		if s.Momentum != 0 {
+			states = []anyvec.Vector{stateVec(s.velocity, v)}
+		}
+		updateVar(v, grad, states, func(grad anyvec.Vector, states []anyvec.Vector) {
+			if s.Momentum != 0 {
+				vel := states[0]
+				vel.Scale(c.MakeNumeric(s.Momentum))
+				vel.Add(grad)
+				if s.Nesterov {
+					lookahead := vel.Copy()
+					lookahead.Scale(c.MakeNumeric(s.Momentum))
+					grad.Add(lookahead)
+				} else {
+					grad.Set(vel)
+				}
+			}
+			grad.Scale(c.MakeNumeric(-rate))
+		})
+	}
+}
@@ -32,10 +32,10 @@ func Sum(s Seq) anydiff.Res {
 	if sum == nil {
 		return anydiff.NewConst(s.Creator().MakeVector(0))
 	}
-	return &sumRes{
+	return trackRes(&sumRes{
 		In:     s,
 		OutVec: sum,
-	}
+	})
 }
 
 func (s *sumRes) Output() anyvec.Vector {
@@ -58,6 +58,14 @@ func (s *sumRes) Propagate(u anyvec.Vector, g anydiff.Grad) {
 	s.In.Propagate(upstream, g)
 }
 
+func (s *sumRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "Sum",
+		Inputs:    []interface{}{s.In},
+		OutputLen: s.OutVec.Len(),
+	}
+}
+
 type sumEachRes struct {
 	In     Seq
 	OutVec anyvec.Vector
@@ -82,10 +90,10 @@ func SumEach(s Seq) anydiff.Res {
 			sum.Add(expBatch.Packed)
 		}
 	}
-	return &sumEachRes{
+	return trackRes(&sumEachRes{
 		In:     s,
 		OutVec: sum,
-	}
+	})
 }
 
 func (s *sumEachRes) Output() anyvec.Vector {
@@ -107,3 +115,159 @@ func (s *sumEachRes) Propagate(u anyvec.Vector, g anydiff.Grad) {
 	}
 	s.In.Propagate(downstream, g)
 }
+
+func (s *sumEachRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "SumEach",
+		Inputs:    []interface{}{s.In},
+		OutputLen: s.OutVec.Len(),
+	}
+}
+
+// MeanEach averages the outputs of each sequence over
+// time, producing a packed vector of means (one per
+// sequence).
+// Empty sequences are ignored.
+//
+// All timesteps must have the same output size.
+func MeanEach(s Seq) anydiff.Res {
+	if len(s.Output()) == 0 {
+		return anydiff.NewConst(s.Creator().MakeVector(0))
+	}
+	var scalers []float64
+	for _, l := range seqLengths(s.Output()) {
+		if l > 0 {
+			scalers = append(scalers, 1/float64(l))
+		}
+	}
+	c := s.Creator()
+	sum := SumEach(s)
+	return anydiff.ScaleRows(&anydiff.Matrix{
+		Data: sum,
+		Rows: len(scalers),
+		Cols: sum.Output().Len() / len(scalers),
+	}, anydiff.NewConst(c.MakeVectorData(c.MakeNumericList(scalers)))).Data
+}
+
+// MaxEach computes the component-wise maximum of the
+// outputs of each sequence over time, producing a packed
+// vector of maxima (one per sequence).
+// Empty sequences are ignored.
+//
+// Gradients are routed to the timestep which produced
+// each maximum.
+//
+// All timesteps must have the same output size.
+func MaxEach(s Seq) anydiff.Res {
+	if len(s.Output()) == 0 {
+		return anydiff.NewConst(s.Creator().MakeVector(0))
+	}
+	c := s.Creator()
+	padded, lengths := Pad(s, c.MakeNumeric(0))
+	maxLen := maxLength(lengths)
+	dim := padded.Output().Len() / (len(lengths) * maxLen)
+	values := c.Float64Slice(padded.Output().Data())
+	var table []int
+	for i, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		for j := 0; j < dim; j++ {
+			best := i*maxLen*dim + j
+			for t := 1; t < l; t++ {
+				idx := (i*maxLen+t)*dim + j
+				if values[idx] > values[best] {
+					best = idx
+				}
+			}
+			table = append(table, best)
+		}
+	}
+	return anydiff.Map(c.MakeMapper(len(values), table), padded)
+}
+
+// LogSumExpEach computes the component-wise logarithm of
+// the sum of exponentials of the outputs of each sequence
+// over time, producing a packed vector of results (one per
+// sequence).
+// Empty sequences are ignored.
+//
+// All timesteps must have the same output size.
+func LogSumExpEach(s Seq) anydiff.Res {
+	if len(s.Output()) == 0 {
+		return anydiff.NewConst(s.Creator().MakeVector(0))
+	}
+	c := s.Creator()
+
+	// Padding with a large negative number rather than -Inf
+	// keeps every intermediate value finite, so the result
+	// can be checked with anydiff.Watch.
+	padded, lengths := Pad(s, c.MakeNumeric(attentionMaskValue))
+	maxLen := maxLength(lengths)
+	dim := padded.Output().Len() / (len(lengths) * maxLen)
+
+	// Gather each component of each non-empty sequence
+	// into a contiguous chunk of timesteps.
+	var table []int
+	for i, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		for j := 0; j < dim; j++ {
+			for t := 0; t < maxLen; t++ {
+				table = append(table, (i*maxLen+t)*dim+j)
+			}
+		}
+	}
+	mapper := c.MakeMapper(padded.Output().Len(), table)
+	return anydiff.LogSumExp(anydiff.Map(mapper, padded), maxLen)
+}
+
+// AttentionEach computes a weighted average of the
+// outputs of each sequence over time, producing a packed
+// vector of averages (one per sequence).
+// Empty sequences are ignored.
+//
+// The weights are computed with anydiff.Attention, using
+// query as the query for every sequence and the outputs
+// of each sequence as both keys and values.
+// Thus, the query must be the same size as the outputs.
+//
+// All timesteps must have the same output size.
+func AttentionEach(s Seq, query anydiff.Res) anydiff.Res {
+	if len(s.Output()) == 0 {
+		return anydiff.NewConst(s.Creator().MakeVector(0))
+	}
+	c := s.Creator()
+	padded, lengths := Pad(s, c.MakeNumeric(0))
+	numSeqs, maxLen := len(lengths), maxLength(lengths)
+	dim := padded.Output().Len() / (numSeqs * maxLen)
+	if query.Output().Len() != dim {
+		panic("query size must match output size")
+	}
+
+	pooled := anydiff.Pool(padded, func(padded anydiff.Res) anydiff.Res {
+		queries := anydiff.AddRepeated(anydiff.NewConst(c.MakeVector(numSeqs*dim)), query)
+		keys := &anydiff.MatrixBatch{Data: padded, Num: numSeqs, Rows: maxLen, Cols: dim}
+		return anydiff.Attention(
+			&anydiff.MatrixBatch{Data: queries, Num: numSeqs, Rows: 1, Cols: dim},
+			keys,
+			keys,
+			attentionMask(c, lengths, 1, 1),
+		).Data
+	})
+
+	var table []int
+	for i, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		for j := 0; j < dim; j++ {
+			table = append(table, i*dim+j)
+		}
+	}
+	if len(table) == pooled.Output().Len() {
+		return pooled
+	}
+	return anydiff.Map(c.MakeMapper(pooled.Output().Len(), table), pooled)
+}
@@ -0,0 +1,132 @@
+package anyseq
+
+import (
+	"reflect"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+func init() {
+	anydiff.RegisterAnomalyKind(&anydiff.AnomalyKind{
+		Type: reflect.TypeOf((*Seq)(nil)).Elem(),
+		NonFinite: func(node interface{}) bool {
+			return batchesNonFinite(node.(Seq).Output())
+		},
+		Wrap: func(node interface{}, report func()) interface{} {
+			return &anomalySeq{In: node.(Seq), Report: report}
+		},
+	})
+}
+
+type watchRes struct {
+	In   Seq
+	Site string
+
+	instrumented Seq
+}
+
+// Watch is like anydiff.Watch, but for sequences.
+//
+// When anomaly detection is enabled, the output of every
+// node in the graph is checked immediately, and every
+// upstream value passed between nodes is checked during
+// back-propagation.
+// If anomaly detection is disabled, s is returned as-is.
+func Watch(s Seq) Seq {
+	if !anydiff.AnomalyDetection() {
+		return s
+	}
+	site := anydiff.AnomalySite(1)
+	anydiff.CheckAnomalies(s, site)
+	return &watchRes{In: s, Site: site}
+}
+
+func (w *watchRes) Creator() anyvec.Creator {
+	return w.In.Creator()
+}
+
+func (w *watchRes) Output() []*Batch {
+	return w.In.Output()
+}
+
+func (w *watchRes) Vars() anydiff.VarSet {
+	return w.In.Vars()
+}
+
+func (w *watchRes) Propagate(u []*Batch, g anydiff.Grad) {
+	if batchesNonFinite(u) {
+		panic(&anydiff.Anomaly{
+			Op:        anydiff.AnomalyOp(w.In),
+			Node:      w.In,
+			Site:      anydiff.NodeSite(w.In),
+			WatchSite: w.Site,
+			Backward:  true,
+		})
+	}
+	if w.instrumented == nil {
+		w.instrumented = anydiff.InstrumentAnomalies(w.In, w.Site).(Seq)
+	}
+	w.instrumented.Propagate(u, g)
+}
+
+func (w *watchRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "Watch",
+		Inputs:    []interface{}{w.In},
+		OutputLen: packedLen(w.In.Output()),
+	}
+}
+
+type anomalySeq struct {
+	In     Seq
+	Report func()
+}
+
+func (a *anomalySeq) Creator() anyvec.Creator {
+	return a.In.Creator()
+}
+
+func (a *anomalySeq) Output() []*Batch {
+	return a.In.Output()
+}
+
+func (a *anomalySeq) Vars() anydiff.VarSet {
+	return a.In.Vars()
+}
+
+func (a *anomalySeq) Propagate(u []*Batch, g anydiff.Grad) {
+	if batchesNonFinite(u) {
+		a.Report()
+	}
+	a.In.Propagate(u, g)
+}
+
+func (a *anomalySeq) NodeInfo() *anydiff.NodeInfo {
+	if gn, ok := a.In.(anydiff.GraphNode); ok {
+		return gn.NodeInfo()
+	}
+	return &anydiff.NodeInfo{Op: anydiff.AnomalyOp(a.In), OutputLen: packedLen(a.In.Output())}
+}
+
+// trackSeq records the construction site of s for anomaly
+// detection and returns s.
+func trackSeq(s Seq) Seq {
+	anydiff.RecordAnomalySite(s)
+	return s
+}
+
+// trackRes is like trackSeq for an anydiff.Res.
+func trackRes(r anydiff.Res) anydiff.Res {
+	anydiff.RecordAnomalySite(r)
+	return r
+}
+
+func batchesNonFinite(batches []*Batch) bool {
+	for _, b := range batches {
+		if b != nil && anydiff.NonFinite(b.Packed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package anyseq
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// attentionMaskValue is added to the attention scores of
+// keys which are not present in a sequence.
+const attentionMaskValue = -1e9
+
+// MultiHeadAttention is a multi-head scaled dot-product
+// attention block.
+//
+// The model vector (the result of each projection) is
+// split evenly between the heads, and every head attends
+// independently using anydiff.Attention.
+type MultiHeadAttention struct {
+	NumHeads int
+
+	// Query is a row-major matrix with one row per model
+	// component and one column per query component.
+	Query *anydiff.Var
+
+	// Key and Value are row-major matrices with one row per
+	// model component and one column per memory component.
+	Key   *anydiff.Var
+	Value *anydiff.Var
+
+	// Output is a row-major matrix with one row per query
+	// component and one column per model component.
+	Output *anydiff.Var
+}
+
+// NewMultiHeadAttention creates a self-attention block
+// with randomly initialized weights.
+//
+// The output vectors are the same size as the inputs.
+// The model size must be divisible by numHeads.
+func NewMultiHeadAttention(c anyvec.Creator, numHeads, inSize,
+	modelSize int) *MultiHeadAttention {
+	return NewCrossAttention(c, numHeads, inSize, inSize, modelSize)
+}
+
+// NewCrossAttention creates an attention block for which
+// the keys and values come from a memory sequence with a
+// possibly different vector size than the queries.
+//
+// The output vectors are the same size as the queries.
+// The model size must be divisible by numHeads.
+func NewCrossAttention(c anyvec.Creator, numHeads, querySize, memorySize,
+	modelSize int) *MultiHeadAttention {
+	if modelSize%numHeads != 0 {
+		panic("model size must be divisible by head count")
+	}
+	return &MultiHeadAttention{
+		NumHeads: numHeads,
+		Query:    randomWeights(c, modelSize, querySize),
+		Key:      randomWeights(c, modelSize, memorySize),
+		Value:    randomWeights(c, modelSize, memorySize),
+		Output:   randomWeights(c, querySize, modelSize),
+	}
+}
+
+// Apply applies self-attention, allowing every timestep
+// of a sequence to attend to every timestep of the same
+// sequence.
+func (m *MultiHeadAttention) Apply(s Seq) Seq {
+	if len(s.Output()) == 0 {
+		return ConstSeq(s.Creator(), nil)
+	}
+	padded, lengths := Pad(s, s.Creator().MakeNumeric(0))
+	return PoolFromVec(padded, func(padded anydiff.Res) Seq {
+		out := m.attend(padded, lengths, padded, lengths)
+		return Unpad(out, lengths)
+	})
+}
+
+// ApplyCross applies encoder-decoder attention, allowing
+// every timestep of a query sequence to attend to every
+// timestep of the corresponding memory sequence.
+//
+// The two batches must contain the same number of
+// sequences, but the sequences may differ in length.
+// The output has the same present maps as the queries.
+func (m *MultiHeadAttention) ApplyCross(queries, memory Seq) Seq {
+	if len(queries.Output()) == 0 {
+		return ConstSeq(queries.Creator(), nil)
+	} else if len(memory.Output()) == 0 {
+		panic("memory must not be empty")
+	}
+	qPadded, qLengths := Pad(queries, queries.Creator().MakeNumeric(0))
+	mPadded, mLengths := Pad(memory, memory.Creator().MakeNumeric(0))
+	if len(qLengths) != len(mLengths) {
+		panic("batch size mismatch")
+	}
+	return PoolFromVec(mPadded, func(mPadded anydiff.Res) Seq {
+		out := m.attend(qPadded, qLengths, mPadded, mLengths)
+		return Unpad(out, qLengths)
+	})
+}
+
+// Parameters returns the block's parameters.
+func (m *MultiHeadAttention) Parameters() []*anydiff.Var {
+	return []*anydiff.Var{m.Query, m.Key, m.Value, m.Output}
+}
+
+// attend applies attention to padded queries and memory
+// vectors, producing padded outputs.
+func (m *MultiHeadAttention) attend(queries anydiff.Res, qLengths []int,
+	memory anydiff.Res, mLengths []int) anydiff.Res {
+	c := queries.Output().Creator()
+	numSeqs := len(qLengths)
+	qSteps, mSteps := maxLength(qLengths), maxLength(mLengths)
+	querySize := queries.Output().Len() / (numSeqs * qSteps)
+	modelSize := m.Query.Vector.Len() / querySize
+	memorySize := m.Key.Vector.Len() / modelSize
+	headSize := modelSize / m.NumHeads
+
+	project := func(in anydiff.Res, rows, cols int, weights *anydiff.Var) *anydiff.MatrixBatch {
+		proj := anydiff.MatMul(false, true,
+			&anydiff.Matrix{Data: in, Rows: rows, Cols: cols},
+			&anydiff.Matrix{Data: weights, Rows: modelSize, Cols: cols})
+		mapper := headMapper(c, numSeqs, rows/numSeqs, m.NumHeads, headSize)
+		return &anydiff.MatrixBatch{
+			Data: anydiff.Map(mapper, proj.Data),
+			Num:  numSeqs * m.NumHeads,
+			Rows: rows / numSeqs,
+			Cols: headSize,
+		}
+	}
+
+	q := project(queries, numSeqs*qSteps, querySize, m.Query)
+	k := project(memory, numSeqs*mSteps, memorySize, m.Key)
+	v := project(memory, numSeqs*mSteps, memorySize, m.Value)
+	heads := anydiff.Attention(q, k, v, attentionMask(c, mLengths, qSteps, m.NumHeads))
+
+	joined := anydiff.MapTranspose(headMapper(c, numSeqs, qSteps, m.NumHeads, headSize),
+		heads.Data)
+	return anydiff.MatMul(false, true,
+		&anydiff.Matrix{Data: joined, Rows: numSeqs * qSteps, Cols: modelSize},
+		&anydiff.Matrix{Data: m.Output, Rows: querySize, Cols: modelSize}).Data
+}
+
+// headMapper creates a mapper which rearranges a padded
+// [sequence][timestep][head][feature] vector into a
+// [sequence][head][timestep][feature] vector.
+//
+// Since the mapper is a permutation, its transpose is its
+// inverse.
+func headMapper(c anyvec.Creator, numSeqs, steps, heads, headSize int) anyvec.Mapper {
+	table := make([]int, 0, numSeqs*steps*heads*headSize)
+	for i := 0; i < numSeqs; i++ {
+		for h := 0; h < heads; h++ {
+			for t := 0; t < steps; t++ {
+				start := ((i*steps+t)*heads + h) * headSize
+				for j := 0; j < headSize; j++ {
+					table = append(table, start+j)
+				}
+			}
+		}
+	}
+	return c.MakeMapper(len(table), table)
+}
+
+// attentionMask creates an additive mask which prevents
+// every head from attending to absent memory timesteps.
+func attentionMask(c anyvec.Creator, mLengths []int, qSteps, heads int) anydiff.Res {
+	mSteps := maxLength(mLengths)
+	data := make([]float64, 0, len(mLengths)*heads*qSteps*mSteps)
+	for _, l := range mLengths {
+		for i := 0; i < heads*qSteps; i++ {
+			for t := 0; t < mSteps; t++ {
+				if t < l {
+					data = append(data, 0)
+				} else {
+					data = append(data, attentionMaskValue)
+				}
+			}
+		}
+	}
+	return anydiff.NewConst(c.MakeVectorData(c.MakeNumericList(data)))
+}
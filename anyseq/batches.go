@@ -0,0 +1,87 @@
+package anyseq
+
+// MergeBatches concatenates batches of sequences along
+// the batch dimension.
+//
+// The resulting batch contains the sequences from the
+// first input, followed by the sequences from the second
+// input, etc.
+// Inputs with no timesteps contribute no sequences, since
+// their batch sizes are unknown.
+func MergeBatches(s ...Seq) Seq {
+	if len(s) == 0 {
+		panic("no batches to merge")
+	}
+	var outSteps [][][]timeRef
+	for j, seq := range s {
+		for i, l := range seqLengths(seq.Output()) {
+			steps := make([][]timeRef, l)
+			for t := range steps {
+				steps[t] = []timeRef{{In: j, Seq: i, Step: t}}
+			}
+			outSteps = append(outSteps, steps)
+		}
+	}
+	return gatherTime("MergeBatches", s, outSteps)
+}
+
+// SplitBatch splits a batch of sequences into consecutive
+// sub-batches with the given numbers of sequences.
+//
+// The sizes must add up to the batch size of s.
+func SplitBatch(s Seq, sizes ...int) []Seq {
+	lengths := seqLengths(s.Output())
+	var total int
+	for _, size := range sizes {
+		total += size
+	}
+	if total != len(lengths) {
+		panic("sizes must add up to batch size")
+	}
+	res := make([]Seq, len(sizes))
+	var offset int
+	for j, size := range sizes {
+		perm := make([]int, size)
+		for i := range perm {
+			perm[i] = offset + i
+		}
+		res[j] = gatherSeqs("SplitBatch", s, lengths, perm)
+		offset += size
+	}
+	return res
+}
+
+// Permute reorders the sequences in a batch.
+//
+// The i-th sequence of the result is the perm[i]-th
+// sequence of s.
+// A permutation can be undone by applying the inverse
+// permutation, e.g. to restore the original order after
+// sorting sequences by length.
+func Permute(s Seq, perm []int) Seq {
+	lengths := seqLengths(s.Output())
+	if len(perm) != len(lengths) {
+		panic("permutation size must match batch size")
+	}
+	seen := make([]bool, len(perm))
+	for _, i := range perm {
+		if i < 0 || i >= len(perm) || seen[i] {
+			panic("invalid permutation")
+		}
+		seen[i] = true
+	}
+	return gatherSeqs("Permute", s, lengths, perm)
+}
+
+// gatherSeqs creates a batch in which the i-th sequence
+// is the indices[i]-th sequence of s.
+func gatherSeqs(op string, s Seq, lengths, indices []int) Seq {
+	outSteps := make([][][]timeRef, len(indices))
+	for i, idx := range indices {
+		outSteps[i] = make([][]timeRef, lengths[idx])
+		for t := range outSteps[i] {
+			outSteps[i][t] = []timeRef{{Seq: idx, Step: t}}
+		}
+	}
+	return gatherTime(op, []Seq{s}, outSteps)
+}
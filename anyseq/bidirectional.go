@@ -0,0 +1,38 @@
+package anyseq
+
+import "github.com/unixpickle/anydiff"
+
+// ConcatInner concatenates the vectors of the sequences at
+// each timestep.
+//
+// All the sequences must have the same present maps, but
+// their vectors may be of different sizes.
+// For each sequence in the batch, the output vector at a
+// timestep is the concatenation of the corresponding
+// vectors from each input, in order.
+func ConcatInner(s ...Seq) Seq {
+	if len(s) == 1 {
+		return s[0]
+	}
+	return MapN(func(n int, v ...anydiff.Res) anydiff.Res {
+		return joinCols(n, v...)
+	}, s...)
+}
+
+// Bidirectional applies one block to the sequences and
+// another block to the reversed sequences, then joins the
+// outputs with ConcatInner.
+//
+// The output of the backward block is reversed before it
+// is joined, so that both outputs line up with the input
+// timesteps.
+//
+// The input is pooled, so it is only back-propagated
+// through once.
+func Bidirectional(s Seq, forward, backward func(s Seq) Seq) Seq {
+	return Pool(s, func(s Seq) Seq {
+		fwdOut := forward(s)
+		bwdOut := Reverse(backward(Reverse(s)))
+		return ConcatInner(fwdOut, bwdOut)
+	})
+}
@@ -0,0 +1,107 @@
+package anyseq
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+type broadcastRes struct {
+	In     anydiff.Res
+	Mapper anyvec.Mapper
+	Out    []*Batch
+}
+
+// Broadcast creates a batch of sequences with the same
+// present maps as like, where every timestep of each
+// sequence contains that sequence's vector from vec.
+//
+// The vec argument contains one vector per non-empty
+// sequence in like, packed and ordered by sequence index
+// (as produced by Tail or SumEach).
+// Gradients are summed over time.
+//
+// Only the present maps of like are used, so no gradient
+// is propagated through like.
+func Broadcast(vec anydiff.Res, like Seq) Seq {
+	batches := like.Output()
+	if len(batches) == 0 {
+		return ConstSeq(like.Creator(), nil)
+	}
+	n := batches[0].NumPresent()
+	if vec.Output().Len()%n != 0 {
+		panic("vector size not divisible by sequence count")
+	}
+	dim := vec.Output().Len() / n
+
+	// indices[i] is the index of sequence i in vec.
+	indices := make([]int, len(batches[0].Present))
+	var idx int
+	for i, pres := range batches[0].Present {
+		if pres {
+			indices[i] = idx
+			idx++
+		}
+	}
+
+	var table []int
+	out := make([]*Batch, len(batches))
+	for t, b := range batches {
+		for i, pres := range b.Present {
+			if pres {
+				for j := 0; j < dim; j++ {
+					table = append(table, indices[i]*dim+j)
+				}
+			}
+		}
+		out[t] = &Batch{Present: b.Present}
+	}
+
+	c := like.Creator()
+	mapper := c.MakeMapper(vec.Output().Len(), table)
+	flat := c.MakeVector(mapper.OutSize())
+	mapper.Map(vec.Output(), flat)
+	var offset int
+	for _, b := range out {
+		size := b.NumPresent() * dim
+		b.Packed = flat.Slice(offset, offset+size)
+		offset += size
+	}
+	return trackSeq(&broadcastRes{In: vec, Mapper: mapper, Out: out})
+}
+
+func (b *broadcastRes) Creator() anyvec.Creator {
+	return b.Mapper.Creator()
+}
+
+func (b *broadcastRes) Output() []*Batch {
+	return b.Out
+}
+
+func (b *broadcastRes) Vars() anydiff.VarSet {
+	return b.In.Vars()
+}
+
+func (b *broadcastRes) Propagate(u []*Batch, g anydiff.Grad) {
+	c := b.Creator()
+	down := c.MakeVector(b.Mapper.InSize())
+	b.Mapper.MapTranspose(c.Concat(batchVecs(u)...), down)
+	b.In.Propagate(down, g)
+}
+
+func (b *broadcastRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "Broadcast",
+		Inputs:    []interface{}{b.In},
+		OutputLen: packedLen(b.Out),
+	}
+}
+
+// AddBroadcast adds each sequence's vector from vec to
+// every timestep of the sequence.
+//
+// The vec argument is laid out like it is for Broadcast.
+func AddBroadcast(s Seq, vec anydiff.Res) Seq {
+	return MapN(func(n int, v ...anydiff.Res) anydiff.Res {
+		return anydiff.Add(v[0], v[1])
+	}, s, Broadcast(vec, s))
+}
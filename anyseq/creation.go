@@ -28,7 +28,7 @@ func ResSeq(c anyvec.Creator, b []*ResBatch) Seq {
 		out[i] = &Batch{Packed: x.Packed.Output(), Present: x.Present}
 		vset = anydiff.MergeVarSets(vset, x.Packed.Vars())
 	}
-	return &resSeq{C: c, In: b, Out: out, V: vset}
+	return trackSeq(&resSeq{C: c, In: b, Out: out, V: vset})
 }
 
 func (r *resSeq) Creator() anyvec.Creator {
@@ -52,6 +52,14 @@ func (r *resSeq) Propagate(u []*Batch, g anydiff.Grad) {
 	}
 }
 
+func (r *resSeq) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "ResSeq",
+		Inputs:    resBatchInputs(r.In),
+		OutputLen: packedLen(r.Out),
+	}
+}
+
 type constSeq struct {
 	C   anyvec.Creator
 	Out []*Batch
@@ -60,7 +68,7 @@ type constSeq struct {
 // ConstSeq creates a batch of sequences from a constant
 // list of batches.
 func ConstSeq(c anyvec.Creator, b []*Batch) Seq {
-	return &constSeq{C: c, Out: b}
+	return trackSeq(&constSeq{C: c, Out: b})
 }
 
 // ConstSeqList creates a constant sequence from a list
@@ -105,6 +113,13 @@ func (c *constSeq) Vars() anydiff.VarSet {
 func (c *constSeq) Propagate(u []*Batch, g anydiff.Grad) {
 }
 
+func (c *constSeq) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "ConstSeq",
+		OutputLen: packedLen(c.Out),
+	}
+}
+
 // SeparateSeqs creates a separate list of vectors for
 // each sequence in the batch.
 func SeparateSeqs(b []*Batch) [][]anyvec.Vector {
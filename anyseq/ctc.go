@@ -0,0 +1,341 @@
+package anyseq
+
+import (
+	"math"
+	"sort"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+type ctcRes struct {
+	In     Seq
+	OutVec anyvec.Vector
+
+	// Grads stores the gradient of each sequence's loss
+	// with respect to the input, packed like the input.
+	Grads [][]float64
+}
+
+// CTCLoss computes the connectionist temporal
+// classification loss for each sequence in a batch.
+//
+// The input contains log probabilities at each timestep,
+// where the last class is the blank label.
+// There must be one label slice per sequence in the
+// batch, containing class indices which are not blanks.
+//
+// The result contains the negative log-likelihood of the
+// labels for each sequence, ordered by sequence index.
+// If a labeling is impossible (e.g. because the sequence
+// is too short), its loss is infinite and its gradient is
+// zero.
+func CTCLoss(s Seq, labels [][]int) anydiff.Res {
+	inBatches := s.Output()
+	if len(inBatches) > 0 && len(inBatches[0].Present) != len(labels) {
+		panic("label count must match sequence count")
+	}
+	seqs := seqValues(s)
+	if seqs == nil {
+		seqs = make([][][]float64, len(labels))
+	}
+
+	losses := make([]float64, len(labels))
+	seqGrads := make([][][]float64, len(labels))
+	for i, seq := range seqs {
+		losses[i], seqGrads[i] = ctcForwardBackward(seq, labels[i])
+	}
+
+	grads := make([][]float64, len(inBatches))
+	timesteps := make([]int, len(labels))
+	for t, b := range inBatches {
+		for i, pres := range b.Present {
+			if pres {
+				grads[t] = append(grads[t], seqGrads[i][timesteps[i]]...)
+				timesteps[i]++
+			}
+		}
+	}
+
+	c := s.Creator()
+	return trackRes(&ctcRes{
+		In:     s,
+		OutVec: c.MakeVectorData(c.MakeNumericList(losses)),
+		Grads:  grads,
+	})
+}
+
+func (c *ctcRes) Output() anyvec.Vector {
+	return c.OutVec
+}
+
+func (c *ctcRes) Vars() anydiff.VarSet {
+	return c.In.Vars()
+}
+
+func (c *ctcRes) Propagate(u anyvec.Vector, g anydiff.Grad) {
+	cr := c.In.Creator()
+	upstream := cr.Float64Slice(u.Data())
+	inBatches := c.In.Output()
+	down := make([]*Batch, len(inBatches))
+	for t, b := range inBatches {
+		data := append([]float64{}, c.Grads[t]...)
+		chunkSize := len(data) / b.NumPresent()
+		var chunk int
+		for i, pres := range b.Present {
+			if pres {
+				for j := chunk * chunkSize; j < (chunk+1)*chunkSize; j++ {
+					data[j] *= upstream[i]
+				}
+				chunk++
+			}
+		}
+		down[t] = &Batch{
+			Packed:  cr.MakeVectorData(cr.MakeNumericList(data)),
+			Present: b.Present,
+		}
+	}
+	c.In.Propagate(down, g)
+}
+
+func (c *ctcRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "CTCLoss",
+		Inputs:    []interface{}{c.In},
+		OutputLen: c.OutVec.Len(),
+	}
+}
+
+// ctcForwardBackward computes the negative log-likelihood
+// of a labeling and its gradient with respect to the log
+// probabilities at each timestep.
+func ctcForwardBackward(seq [][]float64, labels []int) (float64, [][]float64) {
+	grad := make([][]float64, len(seq))
+	for t, x := range seq {
+		grad[t] = make([]float64, len(x))
+	}
+	if len(seq) == 0 {
+		if len(labels) == 0 {
+			return 0, grad
+		}
+		return math.Inf(1), grad
+	}
+
+	blank := len(seq[0]) - 1
+	ext := make([]int, len(labels)*2+1)
+	for i := range ext {
+		ext[i] = blank
+		if i%2 == 1 {
+			label := labels[i/2]
+			if label < 0 || label >= blank {
+				panic("label out of range")
+			}
+			ext[i] = label
+		}
+	}
+	canSkip := func(s int) bool {
+		return s >= 2 && ext[s] != blank && ext[s] != ext[s-2]
+	}
+
+	negInf := math.Inf(-1)
+	alpha := make([][]float64, len(seq))
+	for t := range seq {
+		alpha[t] = make([]float64, len(ext))
+		for s := range ext {
+			alpha[t][s] = negInf
+			if t == 0 {
+				if s < 2 {
+					alpha[t][s] = seq[t][ext[s]]
+				}
+				continue
+			}
+			sum := alpha[t-1][s]
+			if s >= 1 {
+				sum = logAddExp(sum, alpha[t-1][s-1])
+			}
+			if canSkip(s) {
+				sum = logAddExp(sum, alpha[t-1][s-2])
+			}
+			alpha[t][s] = sum + seq[t][ext[s]]
+		}
+	}
+
+	// beta[t][s] excludes the emission at time t.
+	beta := make([][]float64, len(seq))
+	last := len(seq) - 1
+	for t := last; t >= 0; t-- {
+		beta[t] = make([]float64, len(ext))
+		for s := range ext {
+			beta[t][s] = negInf
+			if t == last {
+				if s >= len(ext)-2 {
+					beta[t][s] = 0
+				}
+				continue
+			}
+			sum := beta[t+1][s] + seq[t+1][ext[s]]
+			if s+1 < len(ext) {
+				sum = logAddExp(sum, beta[t+1][s+1]+seq[t+1][ext[s+1]])
+			}
+			if s+2 < len(ext) && canSkip(s+2) {
+				sum = logAddExp(sum, beta[t+1][s+2]+seq[t+1][ext[s+2]])
+			}
+			beta[t][s] = sum
+		}
+	}
+
+	logProb := alpha[last][len(ext)-1]
+	if len(ext) > 1 {
+		logProb = logAddExp(logProb, alpha[last][len(ext)-2])
+	}
+	if math.IsInf(logProb, -1) {
+		return math.Inf(1), grad
+	}
+	for t := range seq {
+		for s, k := range ext {
+			grad[t][k] -= math.Exp(alpha[t][s] + beta[t][s] - logProb)
+		}
+	}
+	return -logProb, grad
+}
+
+// CTCBestPath decodes each sequence in a batch of CTC
+// log probabilities by taking the most likely class at
+// each timestep, merging repeated classes, and removing
+// blanks.
+//
+// The result is ordered by sequence index.
+func CTCBestPath(s Seq) [][]int {
+	var res [][]int
+	for _, seq := range seqValues(s) {
+		labels := []int{}
+		last := -1
+		for _, x := range seq {
+			best := 0
+			for k, val := range x {
+				if val > x[best] {
+					best = k
+				}
+			}
+			if best != last && best != len(x)-1 {
+				labels = append(labels, best)
+			}
+			last = best
+		}
+		res = append(res, labels)
+	}
+	return res
+}
+
+// CTCPrefixBeamSearch decodes each sequence in a batch of
+// CTC log probabilities using prefix beam search.
+//
+// At most beamSize prefixes are kept after each timestep.
+// The result contains the most likely labeling for each
+// sequence, ordered by sequence index.
+func CTCPrefixBeamSearch(s Seq, beamSize int) [][]int {
+	if beamSize < 1 {
+		panic("beam size must be at least 1")
+	}
+	var res [][]int
+	for _, seq := range seqValues(s) {
+		res = append(res, ctcBeamSearch(seq, beamSize))
+	}
+	return res
+}
+
+type ctcBeam struct {
+	Labels []int
+
+	// Log probabilities of the prefix, ending with a blank
+	// and ending with a non-blank, respectively.
+	Blank    float64
+	NonBlank float64
+}
+
+func (c *ctcBeam) total() float64 {
+	return logAddExp(c.Blank, c.NonBlank)
+}
+
+func ctcBeamSearch(seq [][]float64, beamSize int) []int {
+	negInf := math.Inf(-1)
+	beams := []*ctcBeam{{Labels: []int{}, Blank: 0, NonBlank: negInf}}
+	for _, x := range seq {
+		blank := len(x) - 1
+		next := map[string]*ctcBeam{}
+		get := func(labels []int) *ctcBeam {
+			key := labelsKey(labels)
+			if b, ok := next[key]; ok {
+				return b
+			}
+			b := &ctcBeam{Labels: labels, Blank: negInf, NonBlank: negInf}
+			next[key] = b
+			return b
+		}
+		for _, beam := range beams {
+			same := get(beam.Labels)
+			same.Blank = logAddExp(same.Blank, beam.total()+x[blank])
+			if n := len(beam.Labels); n > 0 {
+				lastLabel := beam.Labels[n-1]
+				same.NonBlank = logAddExp(same.NonBlank, beam.NonBlank+x[lastLabel])
+			}
+			for k := 0; k < blank; k++ {
+				extended := append(append([]int{}, beam.Labels...), k)
+				ext := get(extended)
+				if n := len(beam.Labels); n > 0 && beam.Labels[n-1] == k {
+					ext.NonBlank = logAddExp(ext.NonBlank, beam.Blank+x[k])
+				} else {
+					ext.NonBlank = logAddExp(ext.NonBlank, beam.total()+x[k])
+				}
+			}
+		}
+		beams = beams[:0]
+		for _, b := range next {
+			beams = append(beams, b)
+		}
+		sort.Slice(beams, func(i, j int) bool {
+			ti, tj := beams[i].total(), beams[j].total()
+			if ti != tj {
+				return ti > tj
+			}
+			return labelsKey(beams[i].Labels) < labelsKey(beams[j].Labels)
+		})
+		if len(beams) > beamSize {
+			beams = beams[:beamSize]
+		}
+	}
+	return beams[0].Labels
+}
+
+func labelsKey(labels []int) string {
+	res := make([]byte, 0, len(labels)*4)
+	for _, x := range labels {
+		res = append(res, byte(x>>24), byte(x>>16), byte(x>>8), byte(x))
+	}
+	return string(res)
+}
+
+// seqValues converts each sequence in a batch to a list
+// of float64 vectors, ordered by sequence index.
+func seqValues(s Seq) [][][]float64 {
+	c := s.Creator()
+	var res [][][]float64
+	for _, seq := range SeparateSeqs(s.Output()) {
+		vecs := [][]float64{}
+		for _, x := range seq {
+			vecs = append(vecs, c.Float64Slice(x.Data()))
+		}
+		res = append(res, vecs)
+	}
+	return res
+}
+
+func logAddExp(x, y float64) float64 {
+	if math.IsInf(x, -1) {
+		return y
+	} else if math.IsInf(y, -1) {
+		return x
+	}
+	max := math.Max(x, y)
+	return max + math.Log(math.Exp(x-max)+math.Exp(y-max))
+}
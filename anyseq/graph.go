@@ -0,0 +1,34 @@
+package anyseq
+
+import "github.com/unixpickle/anydiff"
+
+func graphInputs(seqs ...Seq) []interface{} {
+	res := make([]interface{}, len(seqs))
+	for i, s := range seqs {
+		res[i] = s
+	}
+	return res
+}
+
+func resBatchInputs(batches []*ResBatch) []interface{} {
+	res := make([]interface{}, len(batches))
+	for i, b := range batches {
+		res[i] = b.Packed
+	}
+	return res
+}
+
+func appendResInputs(in []interface{}, reses []anydiff.Res) []interface{} {
+	for _, r := range reses {
+		in = append(in, r)
+	}
+	return in
+}
+
+func packedLen(batches []*Batch) int {
+	var res int
+	for _, b := range batches {
+		res += b.Packed.Len()
+	}
+	return res
+}
@@ -0,0 +1,61 @@
+package anyseq
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// GRU is a gated recurrent unit block.
+//
+// The state of a GRU is its hidden state, which is also
+// its output.
+type GRU struct {
+	UpdateGate    *Gate
+	ResetGate     *Gate
+	CandidateGate *Gate
+
+	// Init is the initial state for every sequence.
+	Init *anydiff.Var
+}
+
+// NewGRU creates a GRU with randomly initialized weights
+// and a zero initial state.
+func NewGRU(c anyvec.Creator, inSize, hiddenSize int) *GRU {
+	return &GRU{
+		UpdateGate:    NewGate(c, inSize, hiddenSize, hiddenSize),
+		ResetGate:     NewGate(c, inSize, hiddenSize, hiddenSize),
+		CandidateGate: NewGate(c, inSize, hiddenSize, hiddenSize),
+		Init:          anydiff.NewVar(c.MakeVector(hiddenSize)),
+	}
+}
+
+// Apply applies the GRU to a batch of sequences.
+func (g *GRU) Apply(s Seq) Seq {
+	return Scan(s, g.Init, g.step)
+}
+
+// ApplyStates applies the GRU to a batch of sequences,
+// starting from the given initial states.
+// It returns the outputs along with the final states.
+//
+// See ScanStates for details on the states.
+func (g *GRU) ApplyStates(s Seq, init anydiff.Res) (Seq, anyvec.Vector) {
+	return ScanStates(s, init, g.step)
+}
+
+func (g *GRU) step(state, in anydiff.Res, n int) (anydiff.Res, anydiff.Res) {
+	update := anydiff.Sigmoid(g.UpdateGate.Apply(in, state, n))
+	reset := anydiff.Sigmoid(g.ResetGate.Apply(in, state, n))
+	candidate := anydiff.Tanh(g.CandidateGate.Apply(in, anydiff.Mul(reset, state), n))
+	newState := anydiff.Add(state, anydiff.Mul(update, anydiff.Sub(candidate, state)))
+	return newState, newState
+}
+
+// Parameters returns the GRU's parameters.
+func (g *GRU) Parameters() []*anydiff.Var {
+	var res []*anydiff.Var
+	for _, gate := range []*Gate{g.UpdateGate, g.ResetGate, g.CandidateGate} {
+		res = append(res, gate.Parameters()...)
+	}
+	return append(res, g.Init)
+}
@@ -0,0 +1,75 @@
+package anyseq
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// LSTM is a long short-term memory block.
+//
+// The state of an LSTM is the hidden state followed by the
+// cell state, and its outputs are the hidden states.
+type LSTM struct {
+	InputGate  *Gate
+	ForgetGate *Gate
+	OutputGate *Gate
+	CellGate   *Gate
+
+	// Init is the initial state for every sequence.
+	Init *anydiff.Var
+}
+
+// NewLSTM creates an LSTM with randomly initialized
+// weights.
+//
+// The forget gate biases are initialized to 1 and the
+// initial state is initialized to zero.
+func NewLSTM(c anyvec.Creator, inSize, hiddenSize int) *LSTM {
+	res := &LSTM{
+		InputGate:  NewGate(c, inSize, hiddenSize, hiddenSize),
+		ForgetGate: NewGate(c, inSize, hiddenSize, hiddenSize),
+		OutputGate: NewGate(c, inSize, hiddenSize, hiddenSize),
+		CellGate:   NewGate(c, inSize, hiddenSize, hiddenSize),
+		Init:       anydiff.NewVar(c.MakeVector(hiddenSize * 2)),
+	}
+	res.ForgetGate.Biases.Vector.AddScalar(c.MakeNumeric(1))
+	return res
+}
+
+// Apply applies the LSTM to a batch of sequences.
+func (l *LSTM) Apply(s Seq) Seq {
+	return Scan(s, l.Init, l.step)
+}
+
+// ApplyStates applies the LSTM to a batch of sequences,
+// starting from the given initial states.
+// It returns the outputs along with the final states.
+//
+// See ScanStates for details on the states.
+func (l *LSTM) ApplyStates(s Seq, init anydiff.Res) (Seq, anyvec.Vector) {
+	return ScanStates(s, init, l.step)
+}
+
+func (l *LSTM) step(state, in anydiff.Res, n int) (anydiff.Res, anydiff.Res) {
+	hiddenSize := l.Init.Vector.Len() / 2
+	parts := splitCols(state, n, hiddenSize, hiddenSize)
+	hidden, cell := parts[0], parts[1]
+
+	inGate := anydiff.Sigmoid(l.InputGate.Apply(in, hidden, n))
+	forgetGate := anydiff.Sigmoid(l.ForgetGate.Apply(in, hidden, n))
+	outGate := anydiff.Sigmoid(l.OutputGate.Apply(in, hidden, n))
+	cellIn := anydiff.Tanh(l.CellGate.Apply(in, hidden, n))
+
+	newCell := anydiff.Add(anydiff.Mul(forgetGate, cell), anydiff.Mul(inGate, cellIn))
+	newHidden := anydiff.Mul(outGate, anydiff.Tanh(newCell))
+	return joinCols(n, newHidden, newCell), newHidden
+}
+
+// Parameters returns the LSTM's parameters.
+func (l *LSTM) Parameters() []*anydiff.Var {
+	var res []*anydiff.Var
+	for _, g := range []*Gate{l.InputGate, l.ForgetGate, l.OutputGate, l.CellGate} {
+		res = append(res, g.Parameters()...)
+	}
+	return append(res, l.Init)
+}
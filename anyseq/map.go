@@ -41,13 +41,13 @@ func Map(s Seq, f func(v anydiff.Res, n int) anydiff.Res) Seq {
 	for _, x := range pool {
 		allVars.Del(x)
 	}
-	return &mapResult{
+	return trackSeq(&mapResult{
 		In:   s,
 		Pool: pool,
 		Res:  res,
 		Out:  out,
 		V:    allVars,
-	}
+	})
 }
 
 func (m *mapResult) Creator() anyvec.Creator {
@@ -85,6 +85,14 @@ func (m *mapResult) Propagate(u []*Batch, g anydiff.Grad) {
 	m.In.Propagate(downstream, g)
 }
 
+func (m *mapResult) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "Map",
+		Inputs:    appendResInputs([]interface{}{m.In}, m.Res),
+		OutputLen: packedLen(m.Out),
+	}
+}
+
 type mapNResult struct {
 	In   []Seq
 	Pool [][]*anydiff.Var
@@ -148,13 +156,13 @@ func MapN(f func(n int, v ...anydiff.Res) anydiff.Res, s ...Seq) Seq {
 			allVars.Del(y)
 		}
 	}
-	return &mapNResult{
+	return trackSeq(&mapNResult{
 		In:   s,
 		Pool: pool,
 		Res:  res,
 		Out:  out,
 		V:    allVars,
-	}
+	})
 }
 
 func (m *mapNResult) Creator() anyvec.Creator {
@@ -199,3 +207,27 @@ func (m *mapNResult) Propagate(u []*Batch, g anydiff.Grad) {
 		}
 	}
 }
+
+func (m *mapNResult) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "MapN",
+		Inputs:    appendResInputs(graphInputs(m.In...), m.Res),
+		OutputLen: packedLen(m.Out),
+	}
+}
+
+// MapCheckpoint is like Map, except that the work done by
+// f at each timestep is checkpointed with
+// anydiff.Checkpoint.
+//
+// Thus, the intermediate results of f are recomputed
+// during back-propagation rather than kept in memory.
+// Like with anydiff.Checkpoint, f must produce the same
+// result every time it is called for a given timestep.
+func MapCheckpoint(s Seq, f func(v anydiff.Res, n int) anydiff.Res) Seq {
+	return Map(s, func(v anydiff.Res, n int) anydiff.Res {
+		return anydiff.Checkpoint([]anydiff.Res{v}, func(r []anydiff.Res) anydiff.Res {
+			return f(r[0], n)
+		})
+	})
+}
@@ -23,12 +23,12 @@ func Pool(s Seq, f func(s Seq) Seq) Seq {
 		vars.Del(p)
 	}
 
-	return &poolRes{
+	return trackSeq(&poolRes{
 		In:   s,
 		Pool: pool,
 		Res:  out,
 		V:    vars,
-	}
+	})
 }
 
 type poolRes struct {
@@ -72,6 +72,14 @@ func (p *poolRes) Propagate(u []*Batch, grad anydiff.Grad) {
 	p.In.Propagate(downstream, grad)
 }
 
+func (p *poolRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "Pool",
+		Inputs:    []interface{}{p.In, p.Res},
+		OutputLen: packedLen(p.Res.Output()),
+	}
+}
+
 // PoolToVec calls f with a copy of s in such a way that s
 // will only be back-propagated through once.
 func PoolToVec(s Seq, f func(s Seq) anydiff.Res) anydiff.Res {
@@ -90,12 +98,12 @@ func PoolToVec(s Seq, f func(s Seq) anydiff.Res) anydiff.Res {
 		vars.Del(p)
 	}
 
-	return &poolToVecRes{
+	return trackRes(&poolToVecRes{
 		In:   s,
 		Pool: pool,
 		Res:  out,
 		V:    vars,
-	}
+	})
 }
 
 type poolToVecRes struct {
@@ -135,6 +143,14 @@ func (p *poolToVecRes) Propagate(u anyvec.Vector, grad anydiff.Grad) {
 	p.In.Propagate(downstream, grad)
 }
 
+func (p *poolToVecRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "PoolToVec",
+		Inputs:    []interface{}{p.In, p.Res},
+		OutputLen: p.Res.Output().Len(),
+	}
+}
+
 // PoolFromVec calls f with a copy of r in such a way that
 // r will only be back-propagated through once.
 func PoolFromVec(r anydiff.Res, f func(anydiff.Res) Seq) Seq {
@@ -142,12 +158,12 @@ func PoolFromVec(r anydiff.Res, f func(anydiff.Res) Seq) Seq {
 	res := f(pool)
 	v := anydiff.MergeVarSets(r.Vars(), res.Vars())
 	v.Del(pool)
-	return &poolFromVecRes{
+	return trackSeq(&poolFromVecRes{
 		In:   r,
 		Pool: pool,
 		Res:  res,
 		V:    v,
-	}
+	})
 }
 
 type poolFromVecRes struct {
@@ -182,3 +198,11 @@ func (p *poolFromVecRes) Propagate(upstream []*Batch, grad anydiff.Grad) {
 	delete(grad, p.Pool)
 	p.In.Propagate(downstream, grad)
 }
+
+func (p *poolFromVecRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "PoolFromVec",
+		Inputs:    []interface{}{p.In, p.Res},
+		OutputLen: packedLen(p.Res.Output()),
+	}
+}
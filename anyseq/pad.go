@@ -0,0 +1,252 @@
+package anyseq
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+type padRes struct {
+	In     Seq
+	Mapper anyvec.Mapper
+	OutVec anyvec.Vector
+}
+
+// Pad converts a batch of sequences into a dense vector
+// with one row per sequence and timestep.
+//
+// The result is laid out as [sequence][timestep][feature],
+// where every sequence is padded to the length of the
+// longest sequence by filling vectors with padValue.
+// The sequence lengths are returned alongside the result,
+// and can be turned into a mask with PadMask.
+//
+// Padding entries do not depend on the input, so no
+// gradient flows from them.
+//
+// All timesteps must have the same vector size.
+func Pad(s Seq, padValue anyvec.Numeric) (anydiff.Res, []int) {
+	out := s.Output()
+	if len(out) == 0 {
+		return anydiff.NewConst(s.Creator().MakeVector(0)), nil
+	}
+	lengths := seqLengths(out)
+	maxLen := len(out)
+	dim := out[0].Packed.Len() / out[0].NumPresent()
+
+	var flatSize int
+	offsets := make([][]int, len(out))
+	for t, b := range out {
+		if b.Packed.Len() != dim*b.NumPresent() {
+			panic("all timesteps must have the same vector size")
+		}
+		offsets[t] = make([]int, len(b.Present))
+		for i, pres := range b.Present {
+			if pres {
+				offsets[t][i] = flatSize
+				flatSize += dim
+			} else {
+				offsets[t][i] = -1
+			}
+		}
+	}
+
+	table := make([]int, 0, len(lengths)*maxLen*dim)
+	for i := range lengths {
+		for t := 0; t < maxLen; t++ {
+			for j := 0; j < dim; j++ {
+				if offsets[t][i] >= 0 {
+					table = append(table, offsets[t][i]+j)
+				} else {
+					table = append(table, flatSize+j)
+				}
+			}
+		}
+	}
+
+	c := s.Creator()
+	mapper := c.MakeMapper(flatSize+dim, table)
+	outVec := c.MakeVector(mapper.OutSize())
+	padVec := c.MakeVector(dim)
+	padVec.AddScalar(padValue)
+	mapper.Map(c.Concat(append(batchVecs(out), padVec)...), outVec)
+	return trackRes(&padRes{In: s, Mapper: mapper, OutVec: outVec}), lengths
+}
+
+func (p *padRes) Output() anyvec.Vector {
+	return p.OutVec
+}
+
+func (p *padRes) Vars() anydiff.VarSet {
+	return p.In.Vars()
+}
+
+func (p *padRes) Propagate(u anyvec.Vector, g anydiff.Grad) {
+	flat := u.Creator().MakeVector(p.Mapper.InSize())
+	p.Mapper.MapTranspose(u, flat)
+	p.In.Propagate(splitBatches(flat, p.In.Output()), g)
+}
+
+func (p *padRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "Pad",
+		Inputs:    []interface{}{p.In},
+		OutputLen: p.OutVec.Len(),
+	}
+}
+
+type unpadRes struct {
+	In     anydiff.Res
+	Mapper anyvec.Mapper
+	Out    []*Batch
+}
+
+// Unpad reverses the process of Pad, turning a dense
+// vector back into a batch of sequences with the given
+// lengths.
+//
+// The vector is laid out as [sequence][timestep][feature]
+// and padded to the longest length.
+// Padding entries are dropped, so they receive a zero
+// gradient.
+func Unpad(r anydiff.Res, lengths []int) Seq {
+	c := r.Output().Creator()
+	maxLen := maxLength(lengths)
+	if maxLen == 0 {
+		return ConstSeq(c, nil)
+	}
+	if r.Output().Len()%(len(lengths)*maxLen) != 0 {
+		panic("vector size not divisible by padded timestep count")
+	}
+	dim := r.Output().Len() / (len(lengths) * maxLen)
+
+	out := make([]*Batch, maxLen)
+	offsets := make([]int, maxLen)
+	var table []int
+	var offset int
+	for t := range out {
+		present := make([]bool, len(lengths))
+		var n int
+		for i, l := range lengths {
+			if t < l {
+				present[i] = true
+				n++
+				for j := 0; j < dim; j++ {
+					table = append(table, (i*maxLen+t)*dim+j)
+				}
+			}
+		}
+		out[t] = &Batch{Present: present}
+		offsets[t] = offset
+		offset += n * dim
+	}
+
+	mapper := c.MakeMapper(r.Output().Len(), table)
+	flat := c.MakeVector(mapper.OutSize())
+	mapper.Map(r.Output(), flat)
+	for t, b := range out {
+		end := flat.Len()
+		if t+1 < len(out) {
+			end = offsets[t+1]
+		}
+		b.Packed = flat.Slice(offsets[t], end)
+	}
+	return trackSeq(&unpadRes{In: r, Mapper: mapper, Out: out})
+}
+
+func (u *unpadRes) Creator() anyvec.Creator {
+	return u.Mapper.Creator()
+}
+
+func (u *unpadRes) Output() []*Batch {
+	return u.Out
+}
+
+func (u *unpadRes) Vars() anydiff.VarSet {
+	return u.In.Vars()
+}
+
+func (u *unpadRes) Propagate(upstream []*Batch, g anydiff.Grad) {
+	c := u.Creator()
+	down := c.MakeVector(u.Mapper.InSize())
+	u.Mapper.MapTranspose(c.Concat(batchVecs(upstream)...), down)
+	u.In.Propagate(down, g)
+}
+
+func (u *unpadRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "Unpad",
+		Inputs:    []interface{}{u.In},
+		OutputLen: packedLen(u.Out),
+	}
+}
+
+// PadMask creates a mask for the result of Pad.
+//
+// The mask contains one entry per sequence and timestep,
+// laid out as [sequence][timestep].
+// Entries are 1 for present timesteps and 0 for padding.
+func PadMask(c anyvec.Creator, lengths []int) anyvec.Vector {
+	maxLen := maxLength(lengths)
+	data := make([]float64, 0, len(lengths)*maxLen)
+	for _, l := range lengths {
+		for t := 0; t < maxLen; t++ {
+			if t < l {
+				data = append(data, 1)
+			} else {
+				data = append(data, 0)
+			}
+		}
+	}
+	return c.MakeVectorData(c.MakeNumericList(data))
+}
+
+// seqLengths computes the length of every sequence in a
+// batch.
+func seqLengths(batches []*Batch) []int {
+	if len(batches) == 0 {
+		return nil
+	}
+	res := make([]int, len(batches[0].Present))
+	for _, b := range batches {
+		for i, pres := range b.Present {
+			if pres {
+				res[i]++
+			}
+		}
+	}
+	return res
+}
+
+func maxLength(lengths []int) int {
+	var res int
+	for _, l := range lengths {
+		if l > res {
+			res = l
+		}
+	}
+	return res
+}
+
+func batchVecs(batches []*Batch) []anyvec.Vector {
+	res := make([]anyvec.Vector, len(batches))
+	for i, b := range batches {
+		res[i] = b.Packed
+	}
+	return res
+}
+
+// splitBatches splits a vector into batches shaped like
+// the template batches.
+func splitBatches(v anyvec.Vector, template []*Batch) []*Batch {
+	res := make([]*Batch, len(template))
+	var offset int
+	for i, b := range template {
+		size := b.Packed.Len()
+		res[i] = &Batch{
+			Packed:  v.Slice(offset, offset+size),
+			Present: b.Present,
+		}
+		offset += size
+	}
+	return res
+}
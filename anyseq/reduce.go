@@ -113,7 +113,7 @@ func Reduce(s Seq, present []bool) Seq {
 			break
 		}
 	}
-	return res
+	return trackSeq(res)
 }
 
 func (r *reduceRes) Creator() anyvec.Creator {
@@ -143,6 +143,14 @@ func (r *reduceRes) Propagate(u []*Batch, grad anydiff.Grad) {
 	r.In.Propagate(newU, grad)
 }
 
+func (r *reduceRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "Reduce",
+		Inputs:    []interface{}{r.In},
+		OutputLen: packedLen(r.Out),
+	}
+}
+
 type pruneRes struct {
 	In  Seq
 	Out []*Batch
@@ -164,7 +172,7 @@ func Prune(s Seq) Seq {
 		}
 		out[i] = &Batch{Packed: x.Packed, Present: newPres}
 	}
-	return &pruneRes{In: s, Out: out}
+	return trackSeq(&pruneRes{In: s, Out: out})
 }
 
 func (p *pruneRes) Creator() anyvec.Creator {
@@ -187,3 +195,11 @@ func (p *pruneRes) Propagate(u []*Batch, g anydiff.Grad) {
 	}
 	p.In.Propagate(matchingUp, g)
 }
+
+func (p *pruneRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "Prune",
+		Inputs:    []interface{}{p.In},
+		OutputLen: packedLen(p.Out),
+	}
+}
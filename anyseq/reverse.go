@@ -13,10 +13,10 @@ type reverseRes struct {
 // Reverse reverses each sequence in the batch to produce
 // a new batch of reversed sequences.
 func Reverse(s Seq) Seq {
-	return &reverseRes{
+	return trackSeq(&reverseRes{
 		In:  s,
 		Out: reverseSeqs(s.Output()),
-	}
+	})
 }
 
 func (r *reverseRes) Creator() anyvec.Creator {
@@ -35,6 +35,14 @@ func (r *reverseRes) Propagate(u []*Batch, g anydiff.Grad) {
 	r.In.Propagate(reverseSeqs(u), g)
 }
 
+func (r *reverseRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "Reverse",
+		Inputs:    []interface{}{r.In},
+		OutputLen: packedLen(r.Out),
+	}
+}
+
 func reverseSeqs(b []*Batch) []*Batch {
 	if len(b) == 0 {
 		return nil
@@ -0,0 +1,102 @@
+package anyseq
+
+import (
+	"math"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// A Gate computes an affine function of an input and a
+// recurrent state, as used in LSTM and GRU blocks.
+type Gate struct {
+	// InWeights is a row-major matrix with one row per
+	// output component and one column per input component.
+	InWeights *anydiff.Var
+
+	// StateWeights is a row-major matrix with one row per
+	// output component and one column per state component.
+	StateWeights *anydiff.Var
+
+	// Biases has one entry per output component.
+	Biases *anydiff.Var
+}
+
+// NewGate creates a Gate with randomly initialized weights
+// and zero biases.
+func NewGate(c anyvec.Creator, inSize, stateSize, outSize int) *Gate {
+	return &Gate{
+		InWeights:    randomWeights(c, outSize, inSize),
+		StateWeights: randomWeights(c, outSize, stateSize),
+		Biases:       anydiff.NewVar(c.MakeVector(outSize)),
+	}
+}
+
+// Apply applies the gate to a batch of n packed inputs
+// and states, producing n packed outputs.
+func (g *Gate) Apply(in, state anydiff.Res, n int) anydiff.Res {
+	outSize := g.Biases.Vector.Len()
+	inSize := g.InWeights.Vector.Len() / outSize
+	stateSize := g.StateWeights.Vector.Len() / outSize
+	inPart := anydiff.MatMul(false, true,
+		&anydiff.Matrix{Data: in, Rows: n, Cols: inSize},
+		&anydiff.Matrix{Data: g.InWeights, Rows: outSize, Cols: inSize})
+	statePart := anydiff.MatMul(false, true,
+		&anydiff.Matrix{Data: state, Rows: n, Cols: stateSize},
+		&anydiff.Matrix{Data: g.StateWeights, Rows: outSize, Cols: stateSize})
+	return anydiff.AddRepeated(anydiff.Add(inPart.Data, statePart.Data), g.Biases)
+}
+
+// Parameters returns the gate's parameters.
+func (g *Gate) Parameters() []*anydiff.Var {
+	return []*anydiff.Var{g.InWeights, g.StateWeights, g.Biases}
+}
+
+func randomWeights(c anyvec.Creator, rows, cols int) *anydiff.Var {
+	res := c.MakeVector(rows * cols)
+	anyvec.Rand(res, anyvec.Normal, nil)
+	res.Scale(c.MakeNumeric(1 / math.Sqrt(float64(cols))))
+	return anydiff.NewVar(res)
+}
+
+// splitCols splits an n-row matrix into matrices with the
+// given numbers of columns.
+func splitCols(m anydiff.Res, n int, cols ...int) []anydiff.Res {
+	var total int
+	for _, x := range cols {
+		total += x
+	}
+	trans := anydiff.Transpose(&anydiff.Matrix{Data: m, Rows: n, Cols: total})
+	var res []anydiff.Res
+	var offset int
+	for _, x := range cols {
+		part := anydiff.Slice(trans.Data, offset*n, (offset+x)*n)
+		res = append(res, anydiff.Transpose(&anydiff.Matrix{
+			Data: part,
+			Rows: x,
+			Cols: n,
+		}).Data)
+		offset += x
+	}
+	return res
+}
+
+// joinCols joins n-row matrices side by side.
+func joinCols(n int, ms ...anydiff.Res) anydiff.Res {
+	var parts []anydiff.Res
+	var total int
+	for _, m := range ms {
+		cols := m.Output().Len() / n
+		parts = append(parts, anydiff.Transpose(&anydiff.Matrix{
+			Data: m,
+			Rows: n,
+			Cols: cols,
+		}).Data)
+		total += cols
+	}
+	return anydiff.Transpose(&anydiff.Matrix{
+		Data: anydiff.Concat(parts...),
+		Rows: total,
+		Cols: n,
+	}).Data
+}
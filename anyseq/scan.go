@@ -0,0 +1,243 @@
+package anyseq
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// ScanFunc computes one timestep of a recurrence.
+//
+// It is passed the packed states and inputs for the n
+// sequences which are present at the timestep.
+// It returns the packed states for the next timestep and
+// the packed outputs for the current timestep, both of
+// which must be divisible by n.
+type ScanFunc func(state, in anydiff.Res, n int) (newState, out anydiff.Res)
+
+type scanRes struct {
+	In   Seq
+	Pool []*anydiff.Var
+	Res  anydiff.Res
+	Out  []*Batch
+	V    anydiff.VarSet
+}
+
+// Scan applies a recurrent function to each timestep of
+// the sequences in a differentiable manner.
+//
+// The init argument is the state for a single sequence at
+// the first timestep.
+// It is used as the initial state of every sequence.
+//
+// The function f is called once per timestep, in order.
+// When sequences terminate, their states are dropped
+// before the next call to f.
+func Scan(s Seq, init anydiff.Res, f ScanFunc) Seq {
+	out, _ := scan(s, init, true, f)
+	return out
+}
+
+// ScanStates is like Scan, except that init contains a
+// separate initial state for each sequence which is
+// present at the first timestep, and the final states of
+// those sequences are returned along with the outputs.
+//
+// Both init and the final states are packed and ordered
+// by sequence index.
+// The final states are constants, making them suitable
+// for carrying state between calls (e.g. for TBPTT).
+func ScanStates(s Seq, init anydiff.Res, f ScanFunc) (Seq, anyvec.Vector) {
+	return scan(s, init, false, f)
+}
+
+func scan(s Seq, init anydiff.Res, repeat bool, f ScanFunc) (Seq, anyvec.Vector) {
+	inBatches := s.Output()
+	if len(inBatches) == 0 {
+		return ConstSeq(s.Creator(), nil), init.Output().Copy()
+	}
+
+	pool := make([]*anydiff.Var, len(inBatches))
+	for i, x := range inBatches {
+		pool[i] = anydiff.NewVar(x.Packed)
+	}
+	outLens := make([]int, len(inBatches))
+	states := make([]anyvec.Vector, len(inBatches))
+	res := anydiff.Pool(init, func(init anydiff.Res) anydiff.Res {
+		state := init
+		if repeat {
+			state = repeatState(init, inBatches[0].NumPresent())
+		}
+		return scanStep(inBatches, pool, outLens, states, nil, 0, state, f)
+	})
+
+	out := make([]*Batch, len(inBatches))
+	var offset int
+	for i, x := range inBatches {
+		out[i] = &Batch{
+			Packed:  res.Output().Slice(offset, offset+outLens[i]),
+			Present: x.Present,
+		}
+		offset += outLens[i]
+	}
+
+	vars := anydiff.MergeVarSets(s.Vars(), res.Vars())
+	for _, x := range pool {
+		vars.Del(x)
+	}
+	return trackSeq(&scanRes{
+		In:   s,
+		Pool: pool,
+		Res:  res,
+		Out:  out,
+		V:    vars,
+	}), finalStates(inBatches, states)
+}
+
+// scanStep pools the state for timestep t and recursively
+// computes the remaining timesteps.
+//
+// Outputs are accumulated in outs and concatenated once
+// every timestep has been computed, so that no output is
+// copied more than once.
+func scanStep(batches []*Batch, pool []*anydiff.Var, outLens []int,
+	states []anyvec.Vector, outs []anydiff.Res, t int, state anydiff.Res,
+	f ScanFunc) anydiff.Res {
+	return anydiff.Pool(state, func(state anydiff.Res) anydiff.Res {
+		n := batches[t].NumPresent()
+		if state.Output().Len()%n != 0 {
+			panic("state size must be divisible by batch size")
+		}
+		newState, out := f(state, pool[t], n)
+		if out.Output().Len()%n != 0 {
+			panic("scanned function must give a batch of outputs")
+		}
+		outLens[t] = out.Output().Len()
+		states[t] = newState.Output()
+		outs = append(outs, out)
+		if t+1 == len(batches) {
+			return anydiff.Concat(outs...)
+		}
+		next := reduceState(newState, batches[t].Present, batches[t+1].Present)
+		return scanStep(batches, pool, outLens, states, outs, t+1, next, f)
+	})
+}
+
+func (s *scanRes) Creator() anyvec.Creator {
+	return s.In.Creator()
+}
+
+func (s *scanRes) Output() []*Batch {
+	return s.Out
+}
+
+func (s *scanRes) Vars() anydiff.VarSet {
+	return s.V
+}
+
+func (s *scanRes) Propagate(u []*Batch, g anydiff.Grad) {
+	propIn := g.Intersects(s.In.Vars())
+	downstream := make([]*Batch, len(s.Out))
+	if propIn {
+		for i, x := range s.Pool {
+			d := x.Vector.Creator().MakeVector(x.Vector.Len())
+			g[x] = d
+			downstream[i] = &Batch{Packed: d, Present: u[i].Present}
+		}
+	}
+
+	upVecs := make([]anyvec.Vector, len(u))
+	for i, x := range u {
+		upVecs[i] = x.Packed
+	}
+	s.Res.Propagate(s.In.Creator().Concat(upVecs...), g)
+
+	if propIn {
+		for _, x := range s.Pool {
+			delete(g, x)
+		}
+		s.In.Propagate(downstream, g)
+	}
+}
+
+func (s *scanRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "Scan",
+		Inputs:    []interface{}{s.In, s.Res},
+		OutputLen: packedLen(s.Out),
+	}
+}
+
+// finalStates packs the state which each sequence had
+// after its last timestep, given the new states produced
+// at every timestep.
+func finalStates(batches []*Batch, states []anyvec.Vector) anyvec.Vector {
+	var res []anyvec.Vector
+	for i, pres := range batches[0].Present {
+		if !pres {
+			continue
+		}
+		t := len(batches) - 1
+		for j := 1; j < len(batches); j++ {
+			if !batches[j].Present[i] {
+				t = j - 1
+				break
+			}
+		}
+		var idx int
+		for _, p := range batches[t].Present[:i] {
+			if p {
+				idx++
+			}
+		}
+		size := states[t].Len() / batches[t].NumPresent()
+		res = append(res, states[t].Slice(idx*size, (idx+1)*size))
+	}
+	return batches[0].Packed.Creator().Concat(res...)
+}
+
+// repeatState packs n copies of a state.
+func repeatState(state anydiff.Res, n int) anydiff.Res {
+	if n == 1 {
+		return state
+	}
+	reps := make([]anydiff.Res, n)
+	for i := range reps {
+		reps[i] = state
+	}
+	return anydiff.Concat(reps...)
+}
+
+// reduceState drops the packed states for sequences which
+// are present in oldPres but not in newPres.
+func reduceState(state anydiff.Res, oldPres, newPres []bool) anydiff.Res {
+	oldCount := (&Batch{Present: oldPres}).NumPresent()
+	newCount := (&Batch{Present: newPres}).NumPresent()
+	if oldCount == newCount {
+		return state
+	}
+	inc := state.Output().Len() / oldCount
+	return anydiff.Pool(state, func(state anydiff.Res) anydiff.Res {
+		var chunks []anydiff.Res
+		var chunkStart, chunkSize int
+		for i, pres := range newPres {
+			if pres {
+				if !oldPres[i] {
+					panic("cannot re-add sequences")
+				}
+				chunkSize += inc
+			} else if oldPres[i] {
+				if chunkSize > 0 {
+					chunks = append(chunks, anydiff.Slice(state, chunkStart,
+						chunkStart+chunkSize))
+					chunkStart += chunkSize
+					chunkSize = 0
+				}
+				chunkStart += inc
+			}
+		}
+		if chunkSize > 0 {
+			chunks = append(chunks, anydiff.Slice(state, chunkStart, chunkStart+chunkSize))
+		}
+		return anydiff.Concat(chunks...)
+	})
+}
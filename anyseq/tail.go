@@ -27,10 +27,10 @@ func Tail(seq Seq) anydiff.Res {
 		panic("sequences may not all be empty")
 	}
 	out := seq.Creator().Concat(outVecs...)
-	return &tailRes{
+	return trackRes(&tailRes{
 		In:     seq,
 		OutVec: out,
-	}
+	})
 }
 
 type tailRes struct {
@@ -70,6 +70,14 @@ func (t *tailRes) Propagate(u anyvec.Vector, g anydiff.Grad) {
 	t.In.Propagate(batchUpstream, g)
 }
 
+func (t *tailRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        "Tail",
+		Inputs:    []interface{}{t.In},
+		OutputLen: t.OutVec.Len(),
+	}
+}
+
 func tailVecRange(s []*Batch, seqIdx int) (t, startIdx, endIdx int) {
 	t = len(s) - 1
 	for i := 1; i < len(s); i++ {
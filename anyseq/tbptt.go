@@ -0,0 +1,88 @@
+package anyseq
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// TBPTT performs truncated back-propagation through time.
+//
+// Sequences are split into windows of consecutive
+// timesteps, and each window is fed through a recurrent
+// block starting from the final states of the previous
+// window.
+// The carried states are treated as constants, so
+// gradients only propagate within each window.
+type TBPTT struct {
+	// Window is the maximum number of timesteps in each
+	// window.
+	Window int
+
+	// Init is the initial state for a single sequence.
+	// It is used for every sequence in the first window.
+	Init anydiff.Res
+
+	// Block applies a recurrent block to a window.
+	//
+	// It is passed one packed initial state per sequence
+	// which is present at the start of the window, ordered
+	// by sequence index.
+	// It must return the outputs for the window, along with
+	// the final state of each of those sequences.
+	//
+	// LSTM.ApplyStates and GRU.ApplyStates can be used
+	// directly as blocks.
+	Block func(s Seq, init anydiff.Res) (Seq, anyvec.Vector)
+
+	// Cost computes the cost for the outputs of a window.
+	//
+	// The start argument is the index of the first timestep
+	// of the window, which may be used to look up the
+	// targets for the window.
+	Cost func(out Seq, start int) anydiff.Res
+}
+
+// Run feeds a batch of sequences through the block one
+// window at a time.
+//
+// For each window, the gradient of the cost is added to
+// g, so that g ends up with the truncated gradient of the
+// total cost.
+// The total cost, summed over every component of every
+// window's cost, is returned.
+//
+// Each window of the input is extracted with Slice, so
+// gradients also flow into the input sequences, truncated
+// the same way as the gradients of the block.
+func (t *TBPTT) Run(s Seq, g anydiff.Grad) float64 {
+	if t.Window <= 0 {
+		panic("window size must be positive")
+	}
+	batches := s.Output()
+	if len(batches) == 0 {
+		return 0
+	}
+	c := s.Creator()
+
+	state := repeatState(t.Init, batches[0].NumPresent())
+	var total float64
+	for start := 0; start < len(batches); start += t.Window {
+		end := start + t.Window
+		if end > len(batches) {
+			end = len(batches)
+		}
+		out, final := t.Block(Slice(s, start, end), state)
+		cost := t.Cost(out, start)
+		total += c.Float64(anyvec.Sum(cost.Output()))
+		if g.Intersects(cost.Vars()) {
+			upstream := c.MakeVector(cost.Output().Len())
+			upstream.AddScalar(c.MakeNumeric(1))
+			cost.Propagate(upstream, g)
+		}
+		if end < len(batches) {
+			carried := &Batch{Packed: final, Present: batches[start].Present}
+			state = anydiff.NewConst(carried.Reduce(batches[end].Present).Packed)
+		}
+	}
+	return total
+}
@@ -0,0 +1,243 @@
+package anyseq
+
+import (
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// Head produces sequences containing the first n
+// timesteps of each sequence.
+//
+// Sequences with n or fewer timesteps are left intact.
+func Head(s Seq, n int) Seq {
+	return Slice(s, 0, n)
+}
+
+// Slice produces sequences containing the timesteps in
+// the range [start, end) of each sequence.
+//
+// Sequences which are too short to contain the entire
+// range are truncated, and sequences which end before
+// start become empty.
+// Sequence indices within the batch are preserved.
+func Slice(s Seq, start, end int) Seq {
+	if start < 0 || end < start {
+		panic("invalid time range")
+	}
+	lengths := seqLengths(s.Output())
+	outSteps := make([][][]timeRef, len(lengths))
+	for i, l := range lengths {
+		for t := start; t < end && t < l; t++ {
+			outSteps[i] = append(outSteps[i], []timeRef{{Seq: i, Step: t}})
+		}
+	}
+	return gatherTime("Slice", []Seq{s}, outSteps)
+}
+
+// Concat concatenates batches of sequences in time.
+//
+// For each sequence index, the resulting sequence
+// contains the timesteps of the corresponding sequence
+// from each input, in order.
+// All non-empty inputs must have the same number of
+// sequences.
+func Concat(s ...Seq) Seq {
+	if len(s) == 0 {
+		panic("no sequences to concatenate")
+	}
+	var outSteps [][][]timeRef
+	for j, seq := range s {
+		lengths := seqLengths(seq.Output())
+		if lengths == nil {
+			continue
+		} else if outSteps == nil {
+			outSteps = make([][][]timeRef, len(lengths))
+		} else if len(lengths) != len(outSteps) {
+			panic("batch size mismatch")
+		}
+		for i, l := range lengths {
+			for t := 0; t < l; t++ {
+				outSteps[i] = append(outSteps[i], []timeRef{{In: j, Seq: i, Step: t}})
+			}
+		}
+	}
+	return gatherTime("Concat", s, outSteps)
+}
+
+// Downsample keeps every stride-th timestep of each
+// sequence, starting with the first timestep.
+//
+// A sequence of length n becomes a sequence of length
+// ceil(n/stride).
+func Downsample(s Seq, stride int) Seq {
+	if stride <= 0 {
+		panic("stride must be positive")
+	}
+	lengths := seqLengths(s.Output())
+	outSteps := make([][][]timeRef, len(lengths))
+	for i, l := range lengths {
+		for t := 0; t < l; t += stride {
+			outSteps[i] = append(outSteps[i], []timeRef{{Seq: i, Step: t}})
+		}
+	}
+	return gatherTime("Downsample", []Seq{s}, outSteps)
+}
+
+// Window produces sequences of sliding windows, where
+// each output vector is the concatenation of size
+// consecutive input vectors.
+//
+// Windows start at every stride-th timestep, and only
+// windows which fit entirely inside a sequence are used.
+// For example, a stride equal to size produces the
+// non-overlapping frame stacking used by pyramidal
+// encoders, dropping any leftover timesteps.
+func Window(s Seq, size, stride int) Seq {
+	if size <= 0 || stride <= 0 {
+		panic("size and stride must be positive")
+	}
+	lengths := seqLengths(s.Output())
+	outSteps := make([][][]timeRef, len(lengths))
+	for i, l := range lengths {
+		for start := 0; start+size <= l; start += stride {
+			refs := make([]timeRef, size)
+			for j := range refs {
+				refs[j] = timeRef{Seq: i, Step: start + j}
+			}
+			outSteps[i] = append(outSteps[i], refs)
+		}
+	}
+	return gatherTime("Window", []Seq{s}, outSteps)
+}
+
+// A timeRef identifies a timestep of a sequence in one of
+// the inputs to gatherTime.
+type timeRef struct {
+	In   int
+	Seq  int
+	Step int
+}
+
+type gatherTimeRes struct {
+	Op     string
+	Ins    []Seq
+	Mapper anyvec.Mapper
+	Out    []*Batch
+	V      anydiff.VarSet
+}
+
+// gatherTime creates a Seq in which every timestep is a
+// concatenation of input timesteps.
+//
+// For the i-th sequence, outSteps[i][t] lists the input
+// timesteps which are joined to make the output vector at
+// timestep t.
+func gatherTime(op string, ins []Seq, outSteps [][][]timeRef) Seq {
+	c := ins[0].Creator()
+
+	// offsets[j][t][i] is the offset of sequence i at
+	// timestep t of input j in the joined input vector.
+	var flatSize int
+	var inVecs []anyvec.Vector
+	offsets := make([][][]int, len(ins))
+	dims := make([][]int, len(ins))
+	for j, in := range ins {
+		for _, b := range in.Output() {
+			dim := b.Packed.Len() / b.NumPresent()
+			stepOffsets := make([]int, len(b.Present))
+			for i, pres := range b.Present {
+				if pres {
+					stepOffsets[i] = flatSize
+					flatSize += dim
+				}
+			}
+			offsets[j] = append(offsets[j], stepOffsets)
+			dims[j] = append(dims[j], dim)
+			inVecs = append(inVecs, b.Packed)
+		}
+	}
+
+	var out []*Batch
+	var sizes []int
+	var table []int
+	for t := 0; ; t++ {
+		present := make([]bool, len(outSteps))
+		start := len(table)
+		vecSize := -1
+		for i, steps := range outSteps {
+			if t >= len(steps) {
+				continue
+			}
+			present[i] = true
+			seqStart := len(table)
+			for _, ref := range steps[t] {
+				offset := offsets[ref.In][ref.Step][ref.Seq]
+				for k := 0; k < dims[ref.In][ref.Step]; k++ {
+					table = append(table, offset+k)
+				}
+			}
+			if vecSize < 0 {
+				vecSize = len(table) - seqStart
+			} else if vecSize != len(table)-seqStart {
+				panic("inconsistent vector sizes")
+			}
+		}
+		if vecSize < 0 {
+			break
+		}
+		out = append(out, &Batch{Present: present})
+		sizes = append(sizes, len(table)-start)
+	}
+	if len(out) == 0 {
+		return ConstSeq(c, nil)
+	}
+
+	mapper := c.MakeMapper(flatSize, table)
+	flat := c.MakeVector(mapper.OutSize())
+	mapper.Map(c.Concat(inVecs...), flat)
+	var offset int
+	for t, b := range out {
+		b.Packed = flat.Slice(offset, offset+sizes[t])
+		offset += sizes[t]
+	}
+
+	v := anydiff.VarSet{}
+	for _, in := range ins {
+		v = anydiff.MergeVarSets(v, in.Vars())
+	}
+	return trackSeq(&gatherTimeRes{Op: op, Ins: ins, Mapper: mapper, Out: out, V: v})
+}
+
+func (g *gatherTimeRes) Creator() anyvec.Creator {
+	return g.Mapper.Creator()
+}
+
+func (g *gatherTimeRes) Output() []*Batch {
+	return g.Out
+}
+
+func (g *gatherTimeRes) Vars() anydiff.VarSet {
+	return g.V
+}
+
+func (g *gatherTimeRes) Propagate(u []*Batch, grad anydiff.Grad) {
+	c := g.Creator()
+	down := c.MakeVector(g.Mapper.InSize())
+	g.Mapper.MapTranspose(c.Concat(batchVecs(u)...), down)
+	var offset int
+	for _, in := range g.Ins {
+		size := packedLen(in.Output())
+		if grad.Intersects(in.Vars()) {
+			in.Propagate(splitBatches(down.Slice(offset, offset+size), in.Output()), grad)
+		}
+		offset += size
+	}
+}
+
+func (g *gatherTimeRes) NodeInfo() *anydiff.NodeInfo {
+	return &anydiff.NodeInfo{
+		Op:        g.Op,
+		Inputs:    graphInputs(g.Ins...),
+		OutputLen: packedLen(g.Out),
+	}
+}
@@ -0,0 +1,41 @@
+package anydiff
+
+import "math"
+
+// Attention computes scaled dot-product attention for a
+// batch of query, key, and value matrices.
+//
+// Each query and key is a row of q and k, respectively.
+// For every matrix in the batch, the result is
+// Softmax(q*k^T/sqrt(d) + mask)*v, where d is the number
+// of columns in q and the softmax is taken across the
+// keys for each query.
+//
+// If mask is non-nil, it contains one entry per query and
+// key for every matrix in the batch, and it is added to
+// the scaled scores before the softmax.
+// Masked entries should be a large negative number rather
+// than negative infinity, since a query for which every
+// key is masked would otherwise produce NaNs.
+func Attention(q, k, v *MatrixBatch, mask Res) *MatrixBatch {
+	if q.Cols != k.Cols {
+		panic("query and key sizes must match")
+	} else if k.Rows != v.Rows {
+		panic("key and value counts must match")
+	}
+	scores := BatchedMatMul(false, true, q, k)
+	c := q.Data.Output().Creator()
+	logits := Scale(scores.Data, c.MakeNumeric(1/math.Sqrt(float64(q.Cols))))
+	if mask != nil {
+		if mask.Output().Len() != logits.Output().Len() {
+			panic("mask size must match score count")
+		}
+		logits = Add(logits, mask)
+	}
+	return BatchedMatMul(false, false, &MatrixBatch{
+		Data: Softmax(logits, k.Rows),
+		Num:  q.Num,
+		Rows: q.Rows,
+		Cols: k.Rows,
+	}, v)
+}
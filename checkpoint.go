@@ -0,0 +1,89 @@
+package anydiff
+
+import "github.com/unixpickle/anyvec"
+
+type checkpointRes struct {
+	Ins    []Res
+	Pools  []*Var
+	F      func(reses []Res) Res
+	OutVec anyvec.Vector
+	V      VarSet
+}
+
+// Checkpoint calls f with pooled versions of the inputs
+// and returns a result equivalent to that of f, except
+// that the intermediate results inside f are not kept
+// around after the forward pass.
+// Instead, f is called again during back-propagation to
+// recompute the graph.
+//
+// This trades computation for memory, and is useful for
+// large graphs which would otherwise keep many
+// intermediate vectors alive until back-propagation.
+//
+// Like Pool, Checkpoint ensures that each input is only
+// back-propagated through once.
+//
+// Since f is called more than once, it must compute the
+// same output every time.
+// It should not be stochastic unless it uses a fixed
+// source of randomness.
+func Checkpoint(inputs []Res, f func(reses []Res) Res) Res {
+	pools := make([]*Var, len(inputs))
+	reses := make([]Res, len(inputs))
+	for i, x := range inputs {
+		pools[i] = NewVar(x.Output())
+		reses[i] = pools[i]
+	}
+	out := f(reses)
+	vars := MergeVarSets(out.Vars())
+	for _, x := range inputs {
+		vars = MergeVarSets(vars, x.Vars())
+	}
+	for _, x := range pools {
+		vars.Del(x)
+	}
+	return trackRes(&checkpointRes{
+		Ins:    inputs,
+		Pools:  pools,
+		F:      f,
+		OutVec: out.Output(),
+		V:      vars,
+	})
+}
+
+func (c *checkpointRes) Output() anyvec.Vector {
+	return c.OutVec
+}
+
+func (c *checkpointRes) Vars() VarSet {
+	return c.V
+}
+
+func (c *checkpointRes) Propagate(u anyvec.Vector, g Grad) {
+	reses := make([]Res, len(c.Pools))
+	propIn := make([]bool, len(c.Ins))
+	for i, x := range c.Pools {
+		reses[i] = x
+		if g.Intersects(c.Ins[i].Vars()) {
+			propIn[i] = true
+			g[x] = x.Vector.Creator().MakeVector(x.Vector.Len())
+		}
+	}
+	c.F(reses).Propagate(u, g)
+	for i, x := range c.Pools {
+		if propIn[i] {
+			down := g[x]
+			delete(g, x)
+			c.Ins[i].Propagate(down, g)
+		}
+	}
+}
+
+func (c *checkpointRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Checkpoint",
+		Inputs:    resInputs(c.Ins),
+		OutputLen: c.OutVec.Len(),
+	}
+}
@@ -0,0 +1,365 @@
+package anydiff
+
+import "github.com/unixpickle/anyvec"
+
+// Conv2D describes a 2-D convolution over a batch of
+// images.
+//
+// Images are stored in row-major order, with the depth
+// components of each pixel stored contiguously.
+// Batches of images are packed one after another.
+//
+// Filters are stored in the same format as images, with
+// the filters packed one after another.
+// Every filter has a depth of InDepth.
+// The output of the convolution is a batch of images with
+// one depth component per filter.
+type Conv2D struct {
+	InWidth  int
+	InHeight int
+	InDepth  int
+
+	FilterWidth  int
+	FilterHeight int
+
+	// Strides between applications of the filters.
+	// A value of 0 is treated as 1.
+	StrideX int
+	StrideY int
+
+	// Amounts of zero padding added to each side of the
+	// input images.
+	PadX int
+	PadY int
+
+	// Spacing between the filter components when they are
+	// applied to the input.
+	// A value of 0 is treated as 1.
+	DilationX int
+	DilationY int
+}
+
+// OutWidth returns the width of the output images.
+func (c *Conv2D) OutWidth() int {
+	return convOutSize(c.InWidth, c.FilterWidth, c.StrideX, c.PadX, c.DilationX)
+}
+
+// OutHeight returns the height of the output images.
+func (c *Conv2D) OutHeight() int {
+	return convOutSize(c.InHeight, c.FilterHeight, c.StrideY, c.PadY, c.DilationY)
+}
+
+// Apply applies the filters to a batch of input images.
+//
+// The number of images and filters is inferred from the
+// lengths of in and filters.
+func (c *Conv2D) Apply(in, filters Res) Res {
+	n := c.batchSize(in.Output().Len(), c.InWidth*c.InHeight*c.InDepth)
+	numFilters := c.numFilters(filters)
+	padded := Concat(in, NewConst(in.Output().Creator().MakeVector(1)))
+	patches := &Matrix{
+		Data: Map(c.im2col(in.Output().Creator(), n), padded),
+		Rows: n * c.OutWidth() * c.OutHeight(),
+		Cols: c.filterSize(),
+	}
+	filterMat := &Matrix{Data: filters, Rows: numFilters, Cols: c.filterSize()}
+	return MatMul(false, true, patches, filterMat).Data
+}
+
+// Transpose applies the transpose of the convolution to a
+// batch of images.
+//
+// The input images should have the shape of the outputs
+// of Apply, and the resulting images have the shape of
+// the inputs to Apply.
+// This is sometimes known as a deconvolution.
+func (c *Conv2D) Transpose(in, filters Res) Res {
+	numFilters := c.numFilters(filters)
+	n := c.batchSize(in.Output().Len(), c.OutWidth()*c.OutHeight()*numFilters)
+	inMat := &Matrix{
+		Data: in,
+		Rows: n * c.OutWidth() * c.OutHeight(),
+		Cols: numFilters,
+	}
+	filterMat := &Matrix{Data: filters, Rows: numFilters, Cols: c.filterSize()}
+	patches := MatMul(false, false, inMat, filterMat).Data
+	padded := MapTranspose(c.im2col(in.Output().Creator(), n), patches)
+	return Slice(padded, 0, padded.Output().Len()-1)
+}
+
+func (c *Conv2D) filterSize() int {
+	return c.FilterWidth * c.FilterHeight * c.InDepth
+}
+
+func (c *Conv2D) numFilters(filters Res) int {
+	if filters.Output().Len()%c.filterSize() != 0 {
+		panic("filter size must divide filter vector size")
+	}
+	return filters.Output().Len() / c.filterSize()
+}
+
+func (c *Conv2D) batchSize(inLen, imageSize int) int {
+	if c.OutWidth() <= 0 || c.OutHeight() <= 0 {
+		panic("filter does not fit in padded input")
+	}
+	if inLen%imageSize != 0 {
+		panic("image size must divide input size")
+	}
+	return inLen / imageSize
+}
+
+// im2col creates a mapper from a batch of n images, plus
+// an extra zero component, to a matrix where each row is
+// a patch of an image.
+func (c *Conv2D) im2col(cr anyvec.Creator, n int) anyvec.Mapper {
+	strideX, strideY := defaultOne(c.StrideX), defaultOne(c.StrideY)
+	dilationX, dilationY := defaultOne(c.DilationX), defaultOne(c.DilationY)
+	zeroIdx := n * c.InWidth * c.InHeight * c.InDepth
+	table := make([]int, 0, n*c.OutWidth()*c.OutHeight()*c.filterSize())
+	for b := 0; b < n; b++ {
+		for outY := 0; outY < c.OutHeight(); outY++ {
+			for outX := 0; outX < c.OutWidth(); outX++ {
+				for fy := 0; fy < c.FilterHeight; fy++ {
+					inY := outY*strideY - c.PadY + fy*dilationY
+					for fx := 0; fx < c.FilterWidth; fx++ {
+						inX := outX*strideX - c.PadX + fx*dilationX
+						for z := 0; z < c.InDepth; z++ {
+							if inX < 0 || inY < 0 || inX >= c.InWidth || inY >= c.InHeight {
+								table = append(table, zeroIdx)
+							} else {
+								idx := ((b*c.InHeight+inY)*c.InWidth+inX)*c.InDepth + z
+								table = append(table, idx)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return cr.MakeMapper(zeroIdx+1, table)
+}
+
+// Conv1D describes a 1-D convolution over a batch of
+// sequences.
+//
+// It is equivalent to a Conv2D with images of height 1,
+// and uses the same data format.
+type Conv1D struct {
+	InLength int
+	InDepth  int
+
+	FilterLength int
+
+	// Stride is the stride of the filter.
+	// A value of 0 is treated as 1.
+	Stride int
+
+	// Pad is the amount of zero padding on each side.
+	Pad int
+
+	// Dilation is the spacing between filter components.
+	// A value of 0 is treated as 1.
+	Dilation int
+}
+
+// OutLength returns the length of the output sequences.
+func (c *Conv1D) OutLength() int {
+	return c.conv2D().OutWidth()
+}
+
+// Apply applies the filters to a batch of input
+// sequences.
+func (c *Conv1D) Apply(in, filters Res) Res {
+	return c.conv2D().Apply(in, filters)
+}
+
+// Transpose applies the transpose of the convolution.
+func (c *Conv1D) Transpose(in, filters Res) Res {
+	return c.conv2D().Transpose(in, filters)
+}
+
+func (c *Conv1D) conv2D() *Conv2D {
+	return &Conv2D{
+		InWidth:      c.InLength,
+		InHeight:     1,
+		InDepth:      c.InDepth,
+		FilterWidth:  c.FilterLength,
+		FilterHeight: 1,
+		StrideX:      c.Stride,
+		PadX:         c.Pad,
+		DilationX:    c.Dilation,
+	}
+}
+
+// MaxPool2D describes a max-pooling layer for a batch of
+// images in the format used by Conv2D.
+//
+// Each depth component is pooled separately.
+type MaxPool2D struct {
+	InWidth  int
+	InHeight int
+	InDepth  int
+
+	Width  int
+	Height int
+
+	// Strides between pooling windows.
+	// A value of 0 is treated as the window size.
+	StrideX int
+	StrideY int
+}
+
+// OutWidth returns the width of the output images.
+func (m *MaxPool2D) OutWidth() int {
+	return poolGeometry(*m).OutWidth()
+}
+
+// OutHeight returns the height of the output images.
+func (m *MaxPool2D) OutHeight() int {
+	return poolGeometry(*m).OutHeight()
+}
+
+// Apply applies max-pooling to a batch of images.
+func (m *MaxPool2D) Apply(in Res) Res {
+	c := in.Output().Creator()
+	values := c.Float64Slice(in.Output().Data())
+	geom := poolGeometry(*m)
+	n := geom.batchSize(len(values))
+	numOut := n * geom.OutWidth() * geom.OutHeight() * geom.InDepth
+	table := make([]int, 0, numOut)
+	var window []int
+	for outIdx := 0; outIdx < numOut; outIdx++ {
+		window = geom.window(outIdx, window[:0])
+		best := window[0]
+		for _, idx := range window[1:] {
+			if values[idx] > values[best] {
+				best = idx
+			}
+		}
+		table = append(table, best)
+	}
+	return Map(c.MakeMapper(len(values), table), in)
+}
+
+// AvgPool2D describes an average-pooling layer for a
+// batch of images in the format used by Conv2D.
+//
+// Each depth component is pooled separately.
+type AvgPool2D struct {
+	InWidth  int
+	InHeight int
+	InDepth  int
+
+	Width  int
+	Height int
+
+	// Strides between pooling windows.
+	// A value of 0 is treated as the window size.
+	StrideX int
+	StrideY int
+}
+
+// OutWidth returns the width of the output images.
+func (a *AvgPool2D) OutWidth() int {
+	return poolGeometry(*a).OutWidth()
+}
+
+// OutHeight returns the height of the output images.
+func (a *AvgPool2D) OutHeight() int {
+	return poolGeometry(*a).OutHeight()
+}
+
+// Apply applies average-pooling to a batch of images.
+func (a *AvgPool2D) Apply(in Res) Res {
+	c := in.Output().Creator()
+	geom := poolGeometry(*a)
+	n := geom.batchSize(in.Output().Len())
+	numOut := n * geom.OutWidth() * geom.OutHeight() * geom.InDepth
+	table := make([]int, 0, numOut*geom.Width*geom.Height)
+	for outIdx := 0; outIdx < numOut; outIdx++ {
+		table = geom.window(outIdx, table)
+	}
+	windows := &Matrix{
+		Data: Map(c.MakeMapper(in.Output().Len(), table), in),
+		Rows: numOut,
+		Cols: geom.Width * geom.Height,
+	}
+	return Scale(SumCols(windows), c.MakeNumeric(1/float64(windows.Cols)))
+}
+
+type poolGeometry struct {
+	InWidth  int
+	InHeight int
+	InDepth  int
+
+	Width  int
+	Height int
+
+	StrideX int
+	StrideY int
+}
+
+func (p poolGeometry) strides() (x, y int) {
+	x, y = p.StrideX, p.StrideY
+	if x == 0 {
+		x = p.Width
+	}
+	if y == 0 {
+		y = p.Height
+	}
+	return
+}
+
+func (p poolGeometry) OutWidth() int {
+	strideX, _ := p.strides()
+	return convOutSize(p.InWidth, p.Width, strideX, 0, 1)
+}
+
+func (p poolGeometry) OutHeight() int {
+	_, strideY := p.strides()
+	return convOutSize(p.InHeight, p.Height, strideY, 0, 1)
+}
+
+func (p poolGeometry) batchSize(inLen int) int {
+	if p.OutWidth() <= 0 || p.OutHeight() <= 0 {
+		panic("window does not fit in input")
+	}
+	imageSize := p.InWidth * p.InHeight * p.InDepth
+	if inLen%imageSize != 0 {
+		panic("image size must divide input size")
+	}
+	return inLen / imageSize
+}
+
+// window appends the input indices in the window for the
+// given output index to res.
+func (p poolGeometry) window(outIdx int, res []int) []int {
+	strideX, strideY := p.strides()
+	z := outIdx % p.InDepth
+	outX := (outIdx / p.InDepth) % p.OutWidth()
+	outY := (outIdx / (p.InDepth * p.OutWidth())) % p.OutHeight()
+	b := outIdx / (p.InDepth * p.OutWidth() * p.OutHeight())
+	for y := 0; y < p.Height; y++ {
+		inY := outY*strideY + y
+		for x := 0; x < p.Width; x++ {
+			inX := outX*strideX + x
+			res = append(res, ((b*p.InHeight+inY)*p.InWidth+inX)*p.InDepth+z)
+		}
+	}
+	return res
+}
+
+func convOutSize(inSize, filterSize, stride, pad, dilation int) int {
+	span := defaultOne(dilation)*(filterSize-1) + 1
+	if inSize+2*pad < span {
+		return 0
+	}
+	return (inSize+2*pad-span)/defaultOne(stride) + 1
+}
+
+func defaultOne(x int) int {
+	if x == 0 {
+		return 1
+	}
+	return x
+}
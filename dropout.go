@@ -0,0 +1,63 @@
+package anydiff
+
+import (
+	"math/rand"
+
+	"github.com/unixpickle/anyvec"
+)
+
+// Dropout randomly zeros components of v with probability
+// rate, scaling the remaining components by 1/(1-rate) so
+// that the expected output is v.
+//
+// The mask is sampled once using r (or the global source
+// if r is nil), so back-propagation uses the same mask as
+// the forward pass.
+// If training is false, v is returned unchanged.
+func Dropout(v Res, rate float64, r *rand.Rand, training bool) Res {
+	if rate < 0 || rate >= 1 {
+		panic("dropout rate must be in [0, 1)")
+	}
+	if !training || rate == 0 {
+		return v
+	}
+	c := v.Output().Creator()
+	mask := c.MakeVector(v.Output().Len())
+	anyvec.Rand(mask, anyvec.Uniform, r)
+	anyvec.GreaterThan(mask, c.MakeNumeric(rate))
+	mask.Scale(c.MakeNumeric(1 / (1 - rate)))
+	return Mul(v, NewConst(mask))
+}
+
+// GaussianNoise adds independent normally distributed
+// noise with the given standard deviation to every
+// component of v.
+//
+// The noise is sampled once using r (or the global source
+// if r is nil).
+// If training is false, v is returned unchanged.
+func GaussianNoise(v Res, stddev float64, r *rand.Rand, training bool) Res {
+	if !training || stddev == 0 {
+		return v
+	}
+	c := v.Output().Creator()
+	noise := c.MakeVector(v.Output().Len())
+	anyvec.Rand(noise, anyvec.Normal, r)
+	noise.Scale(c.MakeNumeric(stddev))
+	return Add(v, NewConst(noise))
+}
+
+// DropConnect applies dropout to the entries of a weight
+// matrix, rather than to the activations which the matrix
+// is multiplied by.
+//
+// The result should be used in place of m for a single
+// forward pass, e.g. as an argument to MatMul.
+// See Dropout for details on rate, r, and training.
+func DropConnect(m *Matrix, rate float64, r *rand.Rand, training bool) *Matrix {
+	return &Matrix{
+		Data: Dropout(m.Data, rate, r, training),
+		Rows: m.Rows,
+		Cols: m.Cols,
+	}
+}
@@ -0,0 +1,83 @@
+package anydiff
+
+import "github.com/unixpickle/anyvec"
+
+type embeddingRes struct {
+	Table  *Var
+	Cols   int
+	IDs    []int
+	Mapper anyvec.Mapper
+	OutVec anyvec.Vector
+}
+
+// Embedding looks up rows of an embedding table, which is
+// treated as a matrix with the given number of columns.
+//
+// The result packs the row for each ID one after another.
+// IDs may be repeated.
+//
+// If the gradient for the table is a *RowGrad, then only
+// the looked up rows are stored in the gradient.
+// Otherwise, the gradient is accumulated densely, just
+// like it would be with Map.
+func Embedding(table *Var, cols int, ids []int) Res {
+	if cols <= 0 || table.Vector.Len()%cols != 0 {
+		panic("column count must divide table size")
+	}
+	numRows := table.Vector.Len() / cols
+	mapping := make([]int, 0, len(ids)*cols)
+	for _, id := range ids {
+		if id < 0 || id >= numRows {
+			panic("embedding ID out of range")
+		}
+		for j := 0; j < cols; j++ {
+			mapping = append(mapping, id*cols+j)
+		}
+	}
+	c := table.Vector.Creator()
+	out := c.MakeVector(len(mapping))
+	var mapper anyvec.Mapper
+	if len(mapping) > 0 {
+		mapper = c.MakeMapper(table.Vector.Len(), mapping)
+		mapper.Map(table.Vector, out)
+	}
+	return trackRes(&embeddingRes{
+		Table:  table,
+		Cols:   cols,
+		IDs:    append([]int{}, ids...),
+		Mapper: mapper,
+		OutVec: out,
+	})
+}
+
+func (e *embeddingRes) Output() anyvec.Vector {
+	return e.OutVec
+}
+
+func (e *embeddingRes) Vars() VarSet {
+	return e.Table.Vars()
+}
+
+func (e *embeddingRes) Propagate(u anyvec.Vector, g Grad) {
+	if e.Mapper == nil {
+		return
+	}
+	switch grad := g[e.Table].(type) {
+	case nil:
+	case *RowGrad:
+		if grad.RowSize != e.Cols {
+			panic("row gradient size must match embedding size")
+		}
+		grad.AddRows(e.IDs, u)
+	default:
+		e.Mapper.MapTranspose(u, grad)
+	}
+}
+
+func (e *embeddingRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Embedding",
+		Inputs:    []interface{}{e.Table},
+		OutputLen: e.OutVec.Len(),
+	}
+}
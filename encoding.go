@@ -0,0 +1,492 @@
+package anydiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/unixpickle/anyvec"
+)
+
+// encodingVersion is the current version of the binary
+// and JSON encodings.
+const encodingVersion = 1
+
+var encodingMagic = []byte("anydiff\x00")
+
+const (
+	encodedFloat32 = "float32"
+	encodedFloat64 = "float64"
+)
+
+// NamedVars is a named collection of variables, such as
+// the parameters of a model.
+//
+// A NamedVars can be serialized with encoding/gob,
+// encoding/json, or as raw binary data.
+// Only float32 and float64 vectors can be encoded; other
+// vectors (e.g. from anyfwd) result in an error, since
+// they cannot be encoded without losing information.
+// In JSON, non-finite values are encoded as the strings
+// "NaN", "+Inf", and "-Inf".
+//
+// When data is decoded into a NamedVars, the decoded
+// values are copied into the existing variables with
+// matching names.
+// The numeric type is preserved: float32 data may be
+// loaded into a float64 vector, but loading float64 data
+// into a float32 vector fails since it would lose
+// precision.
+// This makes it possible to restore a checkpoint into the
+// *Vars used by an existing model.
+// The Unmarshal and Gob methods fail if a variable is not
+// already present; use Decode to create new variables
+// with a specific anyvec.Creator.
+type NamedVars map[string]*Var
+
+// MarshalBinary encodes the variables in a versioned
+// binary format.
+func (n NamedVars) MarshalBinary() ([]byte, error) {
+	vecs, err := n.encodedVectors()
+	if err != nil {
+		return nil, err
+	}
+	return encodeBinary(vecs)
+}
+
+// UnmarshalBinary decodes variables which were encoded
+// with MarshalBinary.
+func (n *NamedVars) UnmarshalBinary(data []byte) error {
+	vecs, err := decodeBinary(data)
+	if err != nil {
+		return err
+	}
+	return n.loadEncodedVectors(vecs, nil)
+}
+
+// GobEncode is equivalent to MarshalBinary.
+func (n NamedVars) GobEncode() ([]byte, error) {
+	return n.MarshalBinary()
+}
+
+// GobDecode is equivalent to UnmarshalBinary.
+func (n *NamedVars) GobDecode(data []byte) error {
+	return n.UnmarshalBinary(data)
+}
+
+// MarshalJSON encodes the variables as JSON.
+func (n NamedVars) MarshalJSON() ([]byte, error) {
+	vecs, err := n.encodedVectors()
+	if err != nil {
+		return nil, err
+	}
+	return encodeJSON(vecs)
+}
+
+// UnmarshalJSON decodes variables which were encoded with
+// MarshalJSON.
+func (n *NamedVars) UnmarshalJSON(data []byte) error {
+	vecs, err := decodeJSON(data)
+	if err != nil {
+		return err
+	}
+	return n.loadEncodedVectors(vecs, nil)
+}
+
+// Decode decodes variables which were encoded with either
+// MarshalBinary or MarshalJSON.
+//
+// Existing variables are updated in place, like with
+// UnmarshalBinary.
+// Variables which are not already present are created
+// using c.
+// Just like with existing variables, an error is returned
+// if c uses float32 and the encoded data is float64.
+func (n *NamedVars) Decode(c anyvec.Creator, data []byte) error {
+	var vecs []*encodedVector
+	var err error
+	if bytes.HasPrefix(data, encodingMagic) {
+		vecs, err = decodeBinary(data)
+	} else {
+		vecs, err = decodeJSON(data)
+	}
+	if err != nil {
+		return err
+	}
+	return n.loadEncodedVectors(vecs, c)
+}
+
+func (n NamedVars) encodedVectors() ([]*encodedVector, error) {
+	var res []*encodedVector
+	for _, name := range n.sortedNames() {
+		vec, err := encodeVector(name, n[name].Vector)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, vec)
+	}
+	return res, nil
+}
+
+func (n *NamedVars) loadEncodedVectors(vecs []*encodedVector, c anyvec.Creator) error {
+	if *n == nil {
+		*n = NamedVars{}
+	}
+	for _, vec := range vecs {
+		if v, ok := (*n)[vec.Name]; ok {
+			if err := vec.Load(v.Vector); err != nil {
+				return err
+			}
+		} else if c == nil {
+			return fmt.Errorf("unknown variable: %s", vec.Name)
+		} else {
+			newVec := c.MakeVector(len(vec.Data))
+			if err := vec.Load(newVec); err != nil {
+				return err
+			}
+			(*n)[vec.Name] = NewVar(newVec)
+		}
+	}
+	return nil
+}
+
+func (n NamedVars) sortedNames() []string {
+	var names []string
+	for name := range n {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NamedGrad makes it possible to serialize a Grad by
+// giving names to its variables.
+//
+// A NamedGrad can be serialized with encoding/gob,
+// encoding/json, or as raw binary data.
+// Only the gradients for variables in Names are encoded.
+//
+// When data is decoded into a NamedGrad, every encoded
+// name must correspond to a variable in Names.
+// The decoded vectors are copied into the existing
+// gradient vectors when possible.
+// Otherwise, new vectors are added to Grad using the
+// creators of the corresponding variables.
+// If Grad is nil, it is created.
+type NamedGrad struct {
+	Names NamedVars
+	Grad  Grad
+}
+
+// MarshalBinary encodes the gradient in a versioned
+// binary format.
+func (n *NamedGrad) MarshalBinary() ([]byte, error) {
+	vecs, err := n.encodedVectors()
+	if err != nil {
+		return nil, err
+	}
+	return encodeBinary(vecs)
+}
+
+// UnmarshalBinary decodes a gradient which was encoded
+// with MarshalBinary.
+func (n *NamedGrad) UnmarshalBinary(data []byte) error {
+	vecs, err := decodeBinary(data)
+	if err != nil {
+		return err
+	}
+	return n.loadEncodedVectors(vecs)
+}
+
+// GobEncode is equivalent to MarshalBinary.
+func (n *NamedGrad) GobEncode() ([]byte, error) {
+	return n.MarshalBinary()
+}
+
+// GobDecode is equivalent to UnmarshalBinary.
+func (n *NamedGrad) GobDecode(data []byte) error {
+	return n.UnmarshalBinary(data)
+}
+
+// MarshalJSON encodes the gradient as JSON.
+func (n *NamedGrad) MarshalJSON() ([]byte, error) {
+	vecs, err := n.encodedVectors()
+	if err != nil {
+		return nil, err
+	}
+	return encodeJSON(vecs)
+}
+
+// UnmarshalJSON decodes a gradient which was encoded with
+// MarshalJSON.
+func (n *NamedGrad) UnmarshalJSON(data []byte) error {
+	vecs, err := decodeJSON(data)
+	if err != nil {
+		return err
+	}
+	return n.loadEncodedVectors(vecs)
+}
+
+func (n *NamedGrad) encodedVectors() ([]*encodedVector, error) {
+	var res []*encodedVector
+	for _, name := range n.Names.sortedNames() {
+		if vec, ok := n.Grad[n.Names[name]]; ok {
+			encoded, err := encodeVector(name, vec)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, encoded)
+		}
+	}
+	return res, nil
+}
+
+func (n *NamedGrad) loadEncodedVectors(vecs []*encodedVector) error {
+	if n.Grad == nil {
+		n.Grad = Grad{}
+	}
+	for _, vec := range vecs {
+		v, ok := n.Names[vec.Name]
+		if !ok {
+			return fmt.Errorf("unknown variable: %s", vec.Name)
+		}
+		if gradVec, ok := n.Grad[v]; ok {
+			if err := vec.Load(gradVec); err != nil {
+				return err
+			}
+		} else {
+			gradVec = v.Vector.Creator().MakeVector(v.Vector.Len())
+			if err := vec.Load(gradVec); err != nil {
+				return err
+			}
+			n.Grad[v] = gradVec
+		}
+	}
+	return nil
+}
+
+type encodedVector struct {
+	Name string     `json:"name"`
+	Type string     `json:"type"`
+	Data jsonFloats `json:"data"`
+}
+
+func encodeVector(name string, v anyvec.Vector) (*encodedVector, error) {
+	res := &encodedVector{Name: name}
+	switch data := v.Data().(type) {
+	case []float32:
+		res.Type = encodedFloat32
+		res.Data = make(jsonFloats, len(data))
+		for i, x := range data {
+			res.Data[i] = float64(x)
+		}
+	case []float64:
+		res.Type = encodedFloat64
+		res.Data = data
+	default:
+		return nil, fmt.Errorf("cannot encode %s: unsupported numeric list type %T",
+			name, data)
+	}
+	return res, nil
+}
+
+// Load copies the encoded data into an existing vector.
+//
+// It fails if the vector cannot represent the encoded
+// numeric type without losing precision.
+func (e *encodedVector) Load(v anyvec.Vector) error {
+	if v.Len() != len(e.Data) {
+		return fmt.Errorf("length mismatch for %s: expected %d but got %d",
+			e.Name, v.Len(), len(e.Data))
+	}
+	if e.Type != encodedFloat32 && e.Type != encodedFloat64 {
+		return fmt.Errorf("unknown numeric type: %s", e.Type)
+	}
+	if _, ok := v.Data().([]float32); ok && e.Type == encodedFloat64 {
+		return fmt.Errorf("cannot load %s: float64 data into float32 vector", e.Name)
+	}
+	v.SetData(v.Creator().MakeNumericList(e.Data))
+	return nil
+}
+
+// encodeBinary encodes vectors in the following format:
+//
+//	magic   [8]byte
+//	version uint32
+//	count   uint32
+//	vectors [count]struct {
+//	    nameLen uint32
+//	    name    [nameLen]byte
+//	    typeLen uint32
+//	    type    [typeLen]byte
+//	    dataLen uint32
+//	    data    [dataLen]float32 or [dataLen]float64
+//	}
+//
+// All integers and floats are little endian.
+func encodeBinary(vecs []*encodedVector) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(encodingMagic)
+	writeUint32(&buf, encodingVersion)
+	writeUint32(&buf, len(vecs))
+	for _, vec := range vecs {
+		writeUint32(&buf, len(vec.Name))
+		buf.WriteString(vec.Name)
+		writeUint32(&buf, len(vec.Type))
+		buf.WriteString(vec.Type)
+		writeUint32(&buf, len(vec.Data))
+		for _, x := range vec.Data {
+			if vec.Type == encodedFloat32 {
+				binary.Write(&buf, binary.LittleEndian, math.Float32bits(float32(x)))
+			} else {
+				binary.Write(&buf, binary.LittleEndian, math.Float64bits(x))
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeBinary(data []byte) ([]*encodedVector, error) {
+	r := bytes.NewReader(data)
+	magic := make([]byte, len(encodingMagic))
+	if _, err := r.Read(magic); err != nil || !bytes.Equal(magic, encodingMagic) {
+		return nil, errors.New("invalid magic number")
+	}
+	var version, count uint32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != encodingVersion {
+		return nil, fmt.Errorf("unsupported encoding version: %d", version)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	var res []*encodedVector
+	for i := 0; i < int(count); i++ {
+		name, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		typeName, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		var dataLen uint32
+		if err := binary.Read(r, binary.LittleEndian, &dataLen); err != nil {
+			return nil, err
+		}
+		vec := &encodedVector{Name: name, Type: typeName}
+		if int64(dataLen)*4 > int64(r.Len()) {
+			return nil, errors.New("vector length out of bounds")
+		}
+		switch typeName {
+		case encodedFloat32:
+			vals := make([]float32, dataLen)
+			if err := binary.Read(r, binary.LittleEndian, vals); err != nil {
+				return nil, err
+			}
+			vec.Data = make(jsonFloats, dataLen)
+			for i, x := range vals {
+				vec.Data[i] = float64(x)
+			}
+		case encodedFloat64:
+			vec.Data = make(jsonFloats, dataLen)
+			if err := binary.Read(r, binary.LittleEndian, []float64(vec.Data)); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown numeric type: %s", typeName)
+		}
+		res = append(res, vec)
+	}
+	return res, nil
+}
+
+type encodedJSON struct {
+	Version int              `json:"version"`
+	Vectors []*encodedVector `json:"vectors"`
+}
+
+func encodeJSON(vecs []*encodedVector) ([]byte, error) {
+	return json.Marshal(&encodedJSON{Version: encodingVersion, Vectors: vecs})
+}
+
+func decodeJSON(data []byte) ([]*encodedVector, error) {
+	var obj encodedJSON
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	if obj.Version != encodingVersion {
+		return nil, fmt.Errorf("unsupported encoding version: %d", obj.Version)
+	}
+	return obj.Vectors, nil
+}
+
+// jsonFloats is a list of floats which encodes the
+// non-finite values NaN, +Inf, and -Inf as JSON strings,
+// since JSON numbers cannot represent them.
+type jsonFloats []float64
+
+func (j jsonFloats) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, x := range j {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			buf.WriteString(strconv.Quote(strconv.FormatFloat(x, 'g', -1, 64)))
+		} else {
+			buf.WriteString(strconv.FormatFloat(x, 'g', -1, 64))
+		}
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+func (j *jsonFloats) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	res := make(jsonFloats, len(raw))
+	for i, item := range raw {
+		var str string
+		if err := json.Unmarshal(item, &str); err == nil {
+			x, err := strconv.ParseFloat(str, 64)
+			if err != nil || !(math.IsNaN(x) || math.IsInf(x, 0)) {
+				return fmt.Errorf("invalid non-finite value: %q", str)
+			}
+			res[i] = x
+		} else if err := json.Unmarshal(item, &res[i]); err != nil {
+			return err
+		}
+	}
+	*j = res
+	return nil
+}
+
+func writeUint32(w *bytes.Buffer, x int) {
+	binary.Write(w, binary.LittleEndian, uint32(x))
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	var size uint32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return "", err
+	}
+	if int64(size) > int64(r.Len()) {
+		return "", errors.New("string length out of bounds")
+	}
+	data := make([]byte, size)
+	if _, err := r.Read(data); err != nil && size > 0 {
+		return "", err
+	}
+	return string(data), nil
+}
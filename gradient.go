@@ -1,6 +1,10 @@
 package anydiff
 
-import "github.com/unixpickle/anyvec"
+import (
+	"math"
+
+	"github.com/unixpickle/anyvec"
+)
 
 // A Grad represents a gradient by mapping a set of
 // variables each to their respective gradients.
@@ -51,15 +55,108 @@ func (g Grad) ScaleFloat64(f float64) {
 
 // AddToVars adds the gradient to its variables' vectors,
 // thus performing a step of gradient descent.
+//
+// For a *RowGrad, only the touched rows are updated.
 func (g Grad) AddToVars() {
 	for v, x := range g {
-		v.Vector.Add(x)
+		if rg, ok := x.(*RowGrad); ok {
+			rg.Scatter(rg.Values, v.Vector)
+		} else {
+			v.Vector.Add(x)
+		}
 	}
 }
 
 // Clear zeroes out the gradient.
+//
+// Each *RowGrad is Reset, so that a reused gradient only
+// contains the rows touched after it was cleared.
 func (g Grad) Clear() {
 	for _, x := range g {
-		x.Scale(x.Creator().MakeNumeric(0))
+		if rg, ok := x.(*RowGrad); ok {
+			rg.Reset()
+		} else {
+			x.Scale(x.Creator().MakeNumeric(0))
+		}
+	}
+}
+
+// Copy creates a deep copy of the gradient.
+// The variables are the same, but the gradient vectors
+// are copied.
+func (g Grad) Copy() Grad {
+	res := Grad{}
+	for v, x := range g {
+		res[v] = x.Copy()
+	}
+	return res
+}
+
+// Add adds the gradient g1 to g.
+//
+// Variables which are missing from either gradient are
+// treated as having zero gradients.
+// Thus, variables from g1 which are not in g are copied
+// into g.
+func (g Grad) Add(g1 Grad) {
+	for v, x := range g1 {
+		if vec, ok := g[v]; ok {
+			_, sparse := vec.(*RowGrad)
+			if rg, ok := x.(*RowGrad); ok && !sparse {
+				rg.Scatter(rg.Values, vec)
+			} else {
+				vec.Add(x)
+			}
+		} else {
+			g[v] = x.Copy()
+		}
+	}
+}
+
+// Dot computes the dot product of two gradients, treating
+// each gradient as one long vector.
+//
+// Variables which are missing from either gradient are
+// treated as having zero gradients.
+func (g Grad) Dot(g1 Grad) float64 {
+	var res float64
+	for v, x := range g {
+		if x1, ok := g1[v]; ok {
+			if _, ok := x1.(*RowGrad); ok {
+				x, x1 = x1, x
+			}
+			res += x.Creator().Float64(x.Dot(x1))
+		}
+	}
+	return res
+}
+
+// Norm computes the L2 norm of the gradient, treating the
+// gradient as one long vector.
+func (g Grad) Norm() float64 {
+	return math.Sqrt(g.Dot(g))
+}
+
+// ClipNorm scales the gradient down if necessary so that
+// its norm (as computed by Norm) is at most max.
+//
+// The norm before clipping is returned.
+func (g Grad) ClipNorm(max float64) float64 {
+	norm := g.Norm()
+	if norm > max {
+		g.ScaleFloat64(max / norm)
+	}
+	return norm
+}
+
+// ClipVarNorms is like ClipNorm, but it clips the
+// gradient for each variable separately.
+func (g Grad) ClipVarNorms(max float64) {
+	for _, x := range g {
+		c := x.Creator()
+		norm := math.Sqrt(c.Float64(x.Dot(x)))
+		if norm > max {
+			x.Scale(c.MakeNumeric(max / norm))
+		}
 	}
 }
@@ -0,0 +1,139 @@
+package anydiff
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/unixpickle/anyvec"
+)
+
+// NodeInfo describes a node in a computation graph.
+type NodeInfo struct {
+	// Op is a short name for the operation which produced
+	// the node, e.g. "Tanh".
+	Op string
+
+	// Inputs contains the nodes upon which the node directly
+	// depends.
+	// Inputs are typically Res, MultiRes, or anyseq.Seq
+	// values.
+	Inputs []interface{}
+
+	// OutputLen is the total number of output components.
+	OutputLen int
+}
+
+// A GraphNode is a node in a computation graph which can
+// describe itself.
+//
+// All of the Res and MultiRes types in this package, as
+// well as all of the Seq types in anyseq, implement
+// GraphNode.
+type GraphNode interface {
+	NodeInfo() *NodeInfo
+}
+
+// WriteDOT writes the graph of nodes reachable from the
+// roots to w in the Graphviz DOT language.
+//
+// Nodes which do not implement GraphNode are included,
+// but their inputs are not.
+func WriteDOT(w io.Writer, roots ...interface{}) error {
+	buf := bufio.NewWriter(w)
+	buf.WriteString("digraph G {\n")
+	for _, node := range collectGraph(roots) {
+		fmt.Fprintf(buf, "  n%d [label=\"%s\\n%d\"];\n", node.ID,
+			dotEscaper.Replace(node.Op), node.OutputLen)
+		for _, in := range node.Inputs {
+			fmt.Fprintf(buf, "  n%d -> n%d;\n", in, node.ID)
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.Flush()
+}
+
+// WriteGraphJSON writes the graph of nodes reachable from
+// the roots to w as a JSON object.
+//
+// The object has a single key, "nodes", containing a
+// list of nodes.
+// Each node has an "id", an "op", a Go "type", an
+// "outputLen", and a list of "inputs" given by node IDs.
+// Every node appears after all of its inputs.
+func WriteGraphJSON(w io.Writer, roots ...interface{}) error {
+	obj := map[string]interface{}{"nodes": collectGraph(roots)}
+	return json.NewEncoder(w).Encode(obj)
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+type graphEntry struct {
+	ID        int    `json:"id"`
+	Op        string `json:"op"`
+	Type      string `json:"type"`
+	OutputLen int    `json:"outputLen"`
+	Inputs    []int  `json:"inputs"`
+}
+
+// collectGraph lists the reachable nodes in a topological
+// order, with inputs before outputs.
+func collectGraph(roots []interface{}) []*graphEntry {
+	var res []*graphEntry
+	ids := map[interface{}]int{}
+	var visit func(node interface{}) int
+	visit = func(node interface{}) int {
+		comparable := reflect.TypeOf(node).Comparable()
+		if comparable {
+			if id, ok := ids[node]; ok {
+				return id
+			}
+		}
+		entry := &graphEntry{Type: fmt.Sprintf("%T", node), Inputs: []int{}}
+		if gn, ok := node.(GraphNode); ok {
+			info := gn.NodeInfo()
+			entry.Op = info.Op
+			entry.OutputLen = info.OutputLen
+			for _, in := range info.Inputs {
+				entry.Inputs = append(entry.Inputs, visit(in))
+			}
+		} else {
+			entry.Op = entry.Type
+			switch node := node.(type) {
+			case Res:
+				entry.OutputLen = node.Output().Len()
+			case MultiRes:
+				entry.OutputLen = multiOutputLen(node.Outputs())
+			}
+		}
+		entry.ID = len(res)
+		res = append(res, entry)
+		if comparable {
+			ids[node] = entry.ID
+		}
+		return entry.ID
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+	return res
+}
+
+func resInputs(reses []Res) []interface{} {
+	res := make([]interface{}, len(reses))
+	for i, x := range reses {
+		res[i] = x
+	}
+	return res
+}
+
+func multiOutputLen(vecs []anyvec.Vector) int {
+	var res int
+	for _, v := range vecs {
+		res += v.Len()
+	}
+	return res
+}
@@ -0,0 +1,298 @@
+package anydiff
+
+import "github.com/unixpickle/anyvec"
+
+type softmaxRes struct {
+	In        Res
+	ChunkSize int
+	OutVec    anyvec.Vector
+}
+
+// Softmax computes the softmax function for each chunk in
+// a packed list of chunks.
+// The chunk size must divide the vector length.
+// If chunkSize is 0, it will be treated like the full
+// length of v.
+func Softmax(v Res, chunkSize int) Res {
+	chunkSize = checkChunkSize(v.Output(), chunkSize)
+	out := v.Output().Copy()
+	anyvec.LogSoftmax(out, chunkSize)
+	anyvec.Exp(out)
+	return trackRes(&softmaxRes{
+		In:        v,
+		ChunkSize: chunkSize,
+		OutVec:    out,
+	})
+}
+
+func (s *softmaxRes) Output() anyvec.Vector {
+	return s.OutVec
+}
+
+func (s *softmaxRes) Vars() VarSet {
+	return s.In.Vars()
+}
+
+func (s *softmaxRes) Propagate(u anyvec.Vector, g Grad) {
+	numBatch := u.Len() / s.ChunkSize
+	prod := u.Copy()
+	prod.Mul(s.OutVec)
+	sums := anyvec.SumCols(prod, numBatch)
+	sums.Scale(sums.Creator().MakeNumeric(-1))
+	anyvec.AddChunks(u, sums)
+	u.Mul(s.OutVec)
+	s.In.Propagate(u, g)
+}
+
+func (s *softmaxRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Softmax",
+		Inputs:    []interface{}{s.In},
+		OutputLen: s.OutVec.Len(),
+	}
+}
+
+// lossRes is a loss whose gradient with respect to its
+// input is computed during the forward pass.
+type lossRes struct {
+	Op     string
+	In     Res
+	OutVec anyvec.Vector
+
+	// Grad is the gradient of each chunk's loss with
+	// respect to the chunk.
+	Grad anyvec.Vector
+}
+
+// newLossRes creates a lossRes, applying optional
+// per-chunk weights to the loss and its gradient.
+func newLossRes(op string, in Res, loss, grad, weights anyvec.Vector) Res {
+	if weights != nil {
+		if weights.Len() != loss.Len() {
+			panic("weight count must match chunk count")
+		}
+		loss.Mul(weights)
+		anyvec.ScaleChunks(grad, weights)
+	}
+	return trackRes(&lossRes{Op: op, In: in, OutVec: loss, Grad: grad})
+}
+
+func (l *lossRes) Output() anyvec.Vector {
+	return l.OutVec
+}
+
+func (l *lossRes) Vars() VarSet {
+	return l.In.Vars()
+}
+
+func (l *lossRes) Propagate(u anyvec.Vector, g Grad) {
+	down := l.Grad.Copy()
+	anyvec.ScaleChunks(down, u)
+	l.In.Propagate(down, g)
+}
+
+func (l *lossRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        l.Op,
+		Inputs:    []interface{}{l.In},
+		OutputLen: l.OutVec.Len(),
+	}
+}
+
+// SoftmaxCrossEntropy computes the cross-entropy between
+// target distributions and the softmax of each chunk of
+// logits.
+//
+// The result contains one loss per chunk.
+// The targets should have the same length as the logits,
+// and chunkSize behaves as it does for LogSoftmax.
+//
+// If weights is non-nil, it specifies a weight for each
+// chunk's loss.
+func SoftmaxCrossEntropy(logits Res, targets anyvec.Vector, chunkSize int,
+	weights anyvec.Vector) Res {
+	if logits.Output().Len() != targets.Len() {
+		panic("input sizes must match")
+	}
+	chunkSize = checkChunkSize(targets, chunkSize)
+	numBatch := targets.Len() / chunkSize
+
+	logProbs := logits.Output().Copy()
+	anyvec.LogSoftmax(logProbs, chunkSize)
+	prod := logProbs.Copy()
+	prod.Mul(targets)
+	loss := anyvec.SumCols(prod, numBatch)
+	loss.Scale(loss.Creator().MakeNumeric(-1))
+
+	grad := logProbs
+	anyvec.Exp(grad)
+	anyvec.ScaleChunks(grad, anyvec.SumCols(targets, numBatch))
+	grad.Sub(targets)
+
+	return newLossRes("SoftmaxCrossEntropy", logits, loss, grad, weights)
+}
+
+// NLL computes the negative log-likelihood of each label
+// given chunks of log probabilities, such as those from
+// LogSoftmax.
+//
+// There must be exactly one label per chunk, and each
+// label is an index within its chunk.
+// The result contains one loss per chunk.
+//
+// If weights is non-nil, it specifies a weight for each
+// chunk's loss.
+func NLL(logProbs Res, labels []int, chunkSize int, weights anyvec.Vector) Res {
+	chunkSize = checkChunkSize(logProbs.Output(), chunkSize)
+	oneHot := labelOneHot(logProbs.Output().Creator(), labels, chunkSize,
+		logProbs.Output().Len())
+
+	prod := logProbs.Output().Copy()
+	prod.Mul(oneHot)
+	loss := anyvec.SumCols(prod, len(labels))
+	loss.Scale(loss.Creator().MakeNumeric(-1))
+
+	grad := oneHot
+	grad.Scale(grad.Creator().MakeNumeric(-1))
+
+	return newLossRes("NLL", logProbs, loss, grad, weights)
+}
+
+// BCEWithLogits computes the binary cross-entropy between
+// targets and the sigmoid of logits.
+//
+// The losses of the components in each chunk are summed,
+// so that the result contains one loss per chunk.
+// The targets should have the same length as the logits,
+// and chunkSize behaves as it does for LogSoftmax.
+//
+// If weights is non-nil, it specifies a weight for each
+// chunk's loss.
+func BCEWithLogits(logits Res, targets anyvec.Vector, chunkSize int,
+	weights anyvec.Vector) Res {
+	if logits.Output().Len() != targets.Len() {
+		panic("input sizes must match")
+	}
+	chunkSize = checkChunkSize(targets, chunkSize)
+	numBatch := targets.Len() / chunkSize
+
+	// softplus(x) - t*x is a stable form of the loss.
+	elemLoss := softplus(logits.Output())
+	prod := logits.Output().Copy()
+	prod.Mul(targets)
+	elemLoss.Sub(prod)
+	loss := anyvec.SumCols(elemLoss, numBatch)
+
+	grad := logits.Output().Copy()
+	anyvec.Sigmoid(grad)
+	grad.Sub(targets)
+
+	return newLossRes("BCEWithLogits", logits, loss, grad, weights)
+}
+
+// Hinge computes the multi-class hinge loss for chunks of
+// scores.
+//
+// For each chunk, the loss is the sum over the incorrect
+// classes j of max(0, 1 + s[j] - s[label]).
+// There must be exactly one label per chunk.
+// The result contains one loss per chunk.
+//
+// If weights is non-nil, it specifies a weight for each
+// chunk's loss.
+func Hinge(scores Res, labels []int, chunkSize int, weights anyvec.Vector) Res {
+	chunkSize = checkChunkSize(scores.Output(), chunkSize)
+	c := scores.Output().Creator()
+	oneHot := labelOneHot(c, labels, chunkSize, scores.Output().Len())
+
+	prod := scores.Output().Copy()
+	prod.Mul(oneHot)
+	correct := anyvec.SumCols(prod, len(labels))
+	correct.Scale(c.MakeNumeric(-1))
+
+	margins := scores.Output().Copy()
+	anyvec.AddChunks(margins, correct)
+	margins.AddScalar(c.MakeNumeric(1))
+	anyvec.ClipPos(margins)
+	incorrect := oneHot.Copy()
+	anyvec.Complement(incorrect)
+	margins.Mul(incorrect)
+	loss := anyvec.SumCols(margins, len(labels))
+
+	active := margins
+	anyvec.GreaterThan(active, c.MakeNumeric(0))
+	grad := oneHot
+	anyvec.ScaleChunks(grad, anyvec.SumCols(active, len(labels)))
+	grad.Scale(c.MakeNumeric(-1))
+	grad.Add(active)
+
+	return newLossRes("Hinge", scores, loss, grad, weights)
+}
+
+// Huber computes the Huber loss between predictions and
+// targets.
+//
+// For each component, the loss is 0.5*d^2 if |d| <= delta
+// and delta*(|d| - 0.5*delta) otherwise, where d is the
+// difference between the prediction and the target.
+// The losses of the components in each chunk are summed,
+// so that the result contains one loss per chunk.
+//
+// If weights is non-nil, it specifies a weight for each
+// chunk's loss.
+func Huber(preds Res, targets anyvec.Vector, chunkSize int, delta float64,
+	weights anyvec.Vector) Res {
+	if preds.Output().Len() != targets.Len() {
+		panic("input sizes must match")
+	}
+	chunkSize = checkChunkSize(targets, chunkSize)
+	numBatch := targets.Len() / chunkSize
+	c := targets.Creator()
+
+	diff := preds.Output().Copy()
+	diff.Sub(targets)
+
+	// Clip the difference to [-delta, delta] using the
+	// identity clip(d) = delta - relu(2*delta - relu(d+delta)).
+	grad := diff.Copy()
+	grad.AddScalar(c.MakeNumeric(delta))
+	anyvec.ClipPos(grad)
+	grad.Scale(c.MakeNumeric(-1))
+	grad.AddScalar(c.MakeNumeric(2 * delta))
+	anyvec.ClipPos(grad)
+	grad.Scale(c.MakeNumeric(-1))
+	grad.AddScalar(c.MakeNumeric(delta))
+
+	// With g = clip(d), the loss is g*d - 0.5*g^2.
+	elemLoss := grad.Copy()
+	elemLoss.Scale(c.MakeNumeric(-0.5))
+	elemLoss.Add(diff)
+	elemLoss.Mul(grad)
+	loss := anyvec.SumCols(elemLoss, numBatch)
+
+	return newLossRes("Huber", preds, loss, grad, weights)
+}
+
+func checkChunkSize(v anyvec.Vector, chunkSize int) int {
+	if chunkSize == 0 {
+		chunkSize = v.Len()
+	}
+	if v.Len()%chunkSize != 0 {
+		panic("chunk size must divide vector size")
+	}
+	return chunkSize
+}
+
+func labelOneHot(c anyvec.Creator, labels []int, chunkSize, size int) anyvec.Vector {
+	if len(labels)*chunkSize != size {
+		panic("label count must match chunk count")
+	}
+	data := make([]float64, size)
+	for i, label := range labels {
+		if label < 0 || label >= chunkSize {
+			panic("label out of range")
+		}
+		data[i*chunkSize+label] = 1
+	}
+	return c.MakeVectorData(c.MakeNumericList(data))
+}
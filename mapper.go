@@ -12,11 +12,11 @@ type mapRes struct {
 func Map(m anyvec.Mapper, in Res) Res {
 	out := in.Output().Creator().MakeVector(m.OutSize())
 	m.Map(in.Output(), out)
-	return &mapRes{
+	return trackRes(&mapRes{
 		M:   m,
 		In:  in,
 		Out: out,
-	}
+	})
 }
 
 func (m *mapRes) Output() anyvec.Vector {
@@ -33,6 +33,14 @@ func (m *mapRes) Propagate(u anyvec.Vector, g Grad) {
 	m.In.Propagate(down, g)
 }
 
+func (m *mapRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Map",
+		Inputs:    []interface{}{m.In},
+		OutputLen: m.Out.Len(),
+	}
+}
+
 type mapTransposeRes struct {
 	M   anyvec.Mapper
 	In  Res
@@ -44,11 +52,11 @@ type mapTransposeRes struct {
 func MapTranspose(m anyvec.Mapper, in Res) Res {
 	out := in.Output().Creator().MakeVector(m.InSize())
 	m.MapTranspose(in.Output(), out)
-	return &mapTransposeRes{
+	return trackRes(&mapTransposeRes{
 		M:   m,
 		In:  in,
 		Out: out,
-	}
+	})
 }
 
 func (m *mapTransposeRes) Output() anyvec.Vector {
@@ -64,3 +72,11 @@ func (m *mapTransposeRes) Propagate(u anyvec.Vector, g Grad) {
 	m.M.Map(u, down)
 	m.In.Propagate(down, g)
 }
+
+func (m *mapTransposeRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "MapTranspose",
+		Inputs:    []interface{}{m.In},
+		OutputLen: m.Out.Len(),
+	}
+}
@@ -12,10 +12,10 @@ type tanhRes struct {
 func Tanh(in Res) Res {
 	v := in.Output().Copy()
 	anyvec.Tanh(v)
-	return &tanhRes{
+	return trackRes(&tanhRes{
 		In:     in,
 		OutVec: v,
-	}
+	})
 }
 
 func (t *tanhRes) Output() anyvec.Vector {
@@ -34,6 +34,14 @@ func (t *tanhRes) Propagate(u anyvec.Vector, g Grad) {
 	t.In.Propagate(u, g)
 }
 
+func (t *tanhRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Tanh",
+		Inputs:    []interface{}{t.In},
+		OutputLen: t.OutVec.Len(),
+	}
+}
+
 type sigmoidRes struct {
 	In     Res
 	OutVec anyvec.Vector
@@ -48,10 +56,10 @@ type sigmoidRes struct {
 func Sigmoid(in Res) Res {
 	res := in.Output().Copy()
 	anyvec.Sigmoid(res)
-	return &sigmoidRes{
+	return trackRes(&sigmoidRes{
 		In:     in,
 		OutVec: res,
-	}
+	})
 }
 
 func (s *sigmoidRes) Output() anyvec.Vector {
@@ -70,6 +78,14 @@ func (s *sigmoidRes) Propagate(u anyvec.Vector, g Grad) {
 	s.In.Propagate(u, g)
 }
 
+func (s *sigmoidRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Sigmoid",
+		Inputs:    []interface{}{s.In},
+		OutputLen: s.OutVec.Len(),
+	}
+}
+
 type logSoftmaxRes struct {
 	In        Res
 	ChunkSize int
@@ -90,11 +106,11 @@ func LogSoftmax(v Res, chunkSize int) Res {
 	}
 	out := v.Output().Copy()
 	anyvec.LogSoftmax(out, chunkSize)
-	return &logSoftmaxRes{
+	return trackRes(&logSoftmaxRes{
 		In:        v,
 		ChunkSize: chunkSize,
 		OutVec:    out,
-	}
+	})
 }
 
 func (l *logSoftmaxRes) Output() anyvec.Vector {
@@ -117,6 +133,65 @@ func (l *logSoftmaxRes) Propagate(u anyvec.Vector, g Grad) {
 	l.In.Propagate(u, g)
 }
 
+func (l *logSoftmaxRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "LogSoftmax",
+		Inputs:    []interface{}{l.In},
+		OutputLen: l.OutVec.Len(),
+	}
+}
+
+type logSumExpRes struct {
+	In     Res
+	OutVec anyvec.Vector
+}
+
+// LogSumExp computes the log of the sum of the
+// exponentials of the components in each chunk of a
+// packed list of chunks.
+// The result contains one component per chunk.
+// The chunk size must divide the vector length.
+// If chunkSize is 0, it will be treated like the full
+// length of v.
+func LogSumExp(v Res, chunkSize int) Res {
+	if chunkSize == 0 {
+		chunkSize = v.Output().Len()
+	}
+	if v.Output().Len()%chunkSize != 0 {
+		panic("chunk size must divide vector size")
+	}
+	return trackRes(&logSumExpRes{
+		In:     v,
+		OutVec: anyvec.AddLogs(v.Output(), chunkSize),
+	})
+}
+
+func (l *logSumExpRes) Output() anyvec.Vector {
+	return l.OutVec
+}
+
+func (l *logSumExpRes) Vars() VarSet {
+	return l.In.Vars()
+}
+
+func (l *logSumExpRes) Propagate(u anyvec.Vector, g Grad) {
+	negSums := l.OutVec.Copy()
+	negSums.Scale(negSums.Creator().MakeNumeric(-1))
+	down := l.In.Output().Copy()
+	anyvec.AddChunks(down, negSums)
+	anyvec.Exp(down)
+	anyvec.ScaleChunks(down, u)
+	l.In.Propagate(down, g)
+}
+
+func (l *logSumExpRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "LogSumExp",
+		Inputs:    []interface{}{l.In},
+		OutputLen: l.OutVec.Len(),
+	}
+}
+
 // Square squares the vector components.
 func Square(v Res) Res {
 	return Pow(v, v.Output().Creator().MakeNumeric(2))
@@ -133,11 +208,11 @@ type powRes struct {
 func Pow(v Res, s anyvec.Numeric) Res {
 	out := v.Output().Copy()
 	anyvec.Pow(out, s)
-	return &powRes{
+	return trackRes(&powRes{
 		In:     v,
 		OutVec: out,
 		Power:  s,
-	}
+	})
 }
 
 func (p *powRes) Output() anyvec.Vector {
@@ -162,6 +237,14 @@ func (p *powRes) Propagate(u anyvec.Vector, g Grad) {
 	p.In.Propagate(u, g)
 }
 
+func (p *powRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Pow",
+		Inputs:    []interface{}{p.In},
+		OutputLen: p.OutVec.Len(),
+	}
+}
+
 type clipPosRes struct {
 	In     Res
 	OutVec anyvec.Vector
@@ -172,10 +255,10 @@ type clipPosRes struct {
 func ClipPos(in Res) Res {
 	out := in.Output().Copy()
 	anyvec.ClipPos(out)
-	return &clipPosRes{
+	return trackRes(&clipPosRes{
 		In:     in,
 		OutVec: out,
-	}
+	})
 }
 
 func (c *clipPosRes) Output() anyvec.Vector {
@@ -193,6 +276,14 @@ func (c *clipPosRes) Propagate(u anyvec.Vector, g Grad) {
 	c.In.Propagate(u, g)
 }
 
+func (c *clipPosRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "ClipPos",
+		Inputs:    []interface{}{c.In},
+		OutputLen: c.OutVec.Len(),
+	}
+}
+
 // ClipRange clips values to be in the (exclusive) range.
 func ClipRange(in Res, min, max anyvec.Numeric) Res {
 	return Pool(in, func(in Res) Res {
@@ -228,10 +319,10 @@ type sinRes struct {
 func Sin(in Res) Res {
 	out := in.Output().Copy()
 	anyvec.Sin(out)
-	return &sinRes{
+	return trackRes(&sinRes{
 		In:     in,
 		OutVec: out,
-	}
+	})
 }
 
 func (s *sinRes) Output() anyvec.Vector {
@@ -249,6 +340,14 @@ func (s *sinRes) Propagate(u anyvec.Vector, g Grad) {
 	s.In.Propagate(u, g)
 }
 
+func (s *sinRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Sin",
+		Inputs:    []interface{}{s.In},
+		OutputLen: s.OutVec.Len(),
+	}
+}
+
 type cosRes struct {
 	In     Res
 	OutVec anyvec.Vector
@@ -259,10 +358,10 @@ type cosRes struct {
 func Cos(in Res) Res {
 	out := in.Output().Copy()
 	anyvec.Cos(out)
-	return &cosRes{
+	return trackRes(&cosRes{
 		In:     in,
 		OutVec: out,
-	}
+	})
 }
 
 func (s *cosRes) Output() anyvec.Vector {
@@ -281,6 +380,14 @@ func (s *cosRes) Propagate(u anyvec.Vector, g Grad) {
 	s.In.Propagate(u, g)
 }
 
+func (s *cosRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Cos",
+		Inputs:    []interface{}{s.In},
+		OutputLen: s.OutVec.Len(),
+	}
+}
+
 type expRes struct {
 	OutVec anyvec.Vector
 	In     Res
@@ -290,7 +397,7 @@ type expRes struct {
 func Exp(in Res) Res {
 	expd := in.Output().Copy()
 	anyvec.Exp(expd)
-	return &expRes{OutVec: expd, In: in}
+	return trackRes(&expRes{OutVec: expd, In: in})
 }
 
 func (e *expRes) Output() anyvec.Vector {
@@ -306,6 +413,104 @@ func (e *expRes) Propagate(u anyvec.Vector, g Grad) {
 	e.In.Propagate(u, g)
 }
 
+func (e *expRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Exp",
+		Inputs:    []interface{}{e.In},
+		OutputLen: e.OutVec.Len(),
+	}
+}
+
+type logRes struct {
+	In     Res
+	OutVec anyvec.Vector
+}
+
+// Log computes the natural logarithm of each component
+// of the input.
+func Log(in Res) Res {
+	out := in.Output().Copy()
+	anyvec.Log(out)
+	return trackRes(&logRes{In: in, OutVec: out})
+}
+
+func (l *logRes) Output() anyvec.Vector {
+	return l.OutVec
+}
+
+func (l *logRes) Vars() VarSet {
+	return l.In.Vars()
+}
+
+func (l *logRes) Propagate(u anyvec.Vector, g Grad) {
+	u.Div(l.In.Output())
+	l.In.Propagate(u, g)
+}
+
+func (l *logRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Log",
+		Inputs:    []interface{}{l.In},
+		OutputLen: l.OutVec.Len(),
+	}
+}
+
+type log1pRes struct {
+	In     Res
+	OutVec anyvec.Vector
+}
+
+// Log1p computes log(1+x) for each component x of the
+// input.
+// This remains accurate for inputs close to zero, where
+// directly computing log(1+x) would lose precision.
+func Log1p(in Res) Res {
+	x := in.Output()
+	c := x.Creator()
+
+	// Use log(u)*x/(u-1) with u=1+x, which cancels out the
+	// rounding error in u.
+	// When u rounds to exactly 1, the result is just x.
+	u := x.Copy()
+	u.AddScalar(c.MakeNumeric(1))
+	denom := u.Copy()
+	denom.AddScalar(c.MakeNumeric(-1))
+	isOne := denom.Copy()
+	anyvec.EqualTo(isOne, c.MakeNumeric(0))
+	denom.Add(isOne)
+
+	out := u
+	anyvec.Log(out)
+	out.Mul(x)
+	out.Div(denom)
+	isOne.Mul(x)
+	out.Add(isOne)
+	return trackRes(&log1pRes{In: in, OutVec: out})
+}
+
+func (l *log1pRes) Output() anyvec.Vector {
+	return l.OutVec
+}
+
+func (l *log1pRes) Vars() VarSet {
+	return l.In.Vars()
+}
+
+func (l *log1pRes) Propagate(u anyvec.Vector, g Grad) {
+	denom := l.In.Output().Copy()
+	denom.AddScalar(denom.Creator().MakeNumeric(1))
+	u.Div(denom)
+	l.In.Propagate(u, g)
+}
+
+func (l *log1pRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Log1p",
+		Inputs:    []interface{}{l.In},
+		OutputLen: l.OutVec.Len(),
+	}
+}
+
 type logSigmoidRes struct {
 	OutVec anyvec.Vector
 	In     Res
@@ -316,21 +521,14 @@ type logSigmoidRes struct {
 // This may be more numerically stable than first
 // computing the sigmoid and then computing the log.
 func LogSigmoid(in Res) Res {
-	c := in.Output().Creator()
-	idxMap := make([]int, in.Output().Len())
-	for i := range idxMap {
-		idxMap[i] = i * 2
-	}
-	mapper := c.MakeMapper(len(idxMap)*2, idxMap)
-	logSumMe := c.MakeVector(len(idxMap) * 2)
-	mapper.MapTranspose(in.Output(), logSumMe)
-	logSumMe.Scale(c.MakeNumeric(-1))
-	sum := anyvec.AddLogs(logSumMe, 2)
-	sum.Scale(c.MakeNumeric(-1))
-	return &logSigmoidRes{
+	negIn := in.Output().Copy()
+	negIn.Scale(negIn.Creator().MakeNumeric(-1))
+	sum := softplus(negIn)
+	sum.Scale(sum.Creator().MakeNumeric(-1))
+	return trackRes(&logSigmoidRes{
 		OutVec: sum,
 		In:     in,
-	}
+	})
 }
 
 func (l *logSigmoidRes) Output() anyvec.Vector {
@@ -349,6 +547,67 @@ func (l *logSigmoidRes) Propagate(u anyvec.Vector, g Grad) {
 	l.In.Propagate(u, g)
 }
 
+func (l *logSigmoidRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "LogSigmoid",
+		Inputs:    []interface{}{l.In},
+		OutputLen: l.OutVec.Len(),
+	}
+}
+
+type softplusRes struct {
+	OutVec anyvec.Vector
+	In     Res
+}
+
+// Softplus computes log(1 + exp(x)) for each component x
+// of the input.
+// This is computed in a numerically stable way, even for
+// large values of x.
+func Softplus(in Res) Res {
+	return trackRes(&softplusRes{
+		OutVec: softplus(in.Output()),
+		In:     in,
+	})
+}
+
+func (s *softplusRes) Output() anyvec.Vector {
+	return s.OutVec
+}
+
+func (s *softplusRes) Vars() VarSet {
+	return s.In.Vars()
+}
+
+func (s *softplusRes) Propagate(u anyvec.Vector, g Grad) {
+	downstream := s.In.Output().Copy()
+	anyvec.Sigmoid(downstream)
+	u.Mul(downstream)
+	s.In.Propagate(u, g)
+}
+
+func (s *softplusRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Softplus",
+		Inputs:    []interface{}{s.In},
+		OutputLen: s.OutVec.Len(),
+	}
+}
+
+// softplus computes log(1 + exp(x)) for each component
+// of v by adding each component to 0 in the log domain.
+func softplus(v anyvec.Vector) anyvec.Vector {
+	c := v.Creator()
+	idxMap := make([]int, v.Len())
+	for i := range idxMap {
+		idxMap[i] = i * 2
+	}
+	mapper := c.MakeMapper(len(idxMap)*2, idxMap)
+	logSumMe := c.MakeVector(len(idxMap) * 2)
+	mapper.MapTranspose(v, logSumMe)
+	return anyvec.AddLogs(logSumMe, 2)
+}
+
 type complementRes struct {
 	In     Res
 	OutVec anyvec.Vector
@@ -358,7 +617,7 @@ type complementRes struct {
 func Complement(in Res) Res {
 	compIn := in.Output().Copy()
 	anyvec.Complement(compIn)
-	return &complementRes{In: in, OutVec: compIn}
+	return trackRes(&complementRes{In: in, OutVec: compIn})
 }
 
 func (c *complementRes) Output() anyvec.Vector {
@@ -374,6 +633,14 @@ func (c *complementRes) Propagate(u anyvec.Vector, g Grad) {
 	c.In.Propagate(u, g)
 }
 
+func (c *complementRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Complement",
+		Inputs:    []interface{}{c.In},
+		OutputLen: c.OutVec.Len(),
+	}
+}
+
 // Abs computes the component-wise absolute value.
 func Abs(in Res) Res {
 	sign := in.Output().Copy()
@@ -48,10 +48,10 @@ type sumRowsRes struct {
 // SumRows sums the rows of a matrix.
 func SumRows(m *Matrix) Res {
 	out := anyvec.SumRows(m.Data.Output(), m.Cols)
-	return &sumRowsRes{
+	return trackRes(&sumRowsRes{
 		In:  m,
 		Out: out,
-	}
+	})
 }
 
 func (s *sumRowsRes) Output() anyvec.Vector {
@@ -68,6 +68,14 @@ func (s *sumRowsRes) Propagate(u anyvec.Vector, g Grad) {
 	s.In.Data.Propagate(downstream, g)
 }
 
+func (s *sumRowsRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "SumRows",
+		Inputs:    []interface{}{s.In.Data},
+		OutputLen: s.Out.Len(),
+	}
+}
+
 type sumColsRes struct {
 	In  *Matrix
 	Out anyvec.Vector
@@ -76,10 +84,10 @@ type sumColsRes struct {
 // SumCols sums the columns of a matrix.
 func SumCols(m *Matrix) Res {
 	out := anyvec.SumCols(m.Data.Output(), m.Rows)
-	return &sumColsRes{
+	return trackRes(&sumColsRes{
 		In:  m,
 		Out: out,
-	}
+	})
 }
 
 func (s *sumColsRes) Output() anyvec.Vector {
@@ -96,6 +104,14 @@ func (s *sumColsRes) Propagate(u anyvec.Vector, g Grad) {
 	s.In.Data.Propagate(downstream, g)
 }
 
+func (s *sumColsRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "SumCols",
+		Inputs:    []interface{}{s.In.Data},
+		OutputLen: s.Out.Len(),
+	}
+}
+
 type scaleRowsRes struct {
 	In      *Matrix
 	Scalers Res
@@ -112,12 +128,12 @@ func ScaleRows(m *Matrix, scalers Res) *Matrix {
 	outVec := m.Data.Output().Copy()
 	anyvec.ScaleChunks(outVec, scalers.Output())
 	return &Matrix{
-		Data: &scaleRowsRes{
+		Data: trackRes(&scaleRowsRes{
 			In:      m,
 			Scalers: scalers,
 			Out:     outVec,
 			V:       MergeVarSets(m.Data.Vars(), scalers.Vars()),
-		},
+		}),
 		Rows: m.Rows,
 		Cols: m.Cols,
 	}
@@ -144,6 +160,14 @@ func (s *scaleRowsRes) Propagate(u anyvec.Vector, g Grad) {
 	}
 }
 
+func (s *scaleRowsRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "ScaleRows",
+		Inputs:    []interface{}{s.In.Data, s.Scalers},
+		OutputLen: s.Out.Len(),
+	}
+}
+
 type transposeRes struct {
 	In  *Matrix
 	Out anyvec.Vector
@@ -154,7 +178,7 @@ func Transpose(m *Matrix) *Matrix {
 	data := m.Data.Output().Creator().MakeVector(m.Data.Output().Len())
 	anyvec.Transpose(m.Data.Output(), data, m.Rows)
 	return &Matrix{
-		Data: &transposeRes{In: m, Out: data},
+		Data: trackRes(&transposeRes{In: m, Out: data}),
 		Rows: m.Cols,
 		Cols: m.Rows,
 	}
@@ -174,6 +198,14 @@ func (t *transposeRes) Propagate(u anyvec.Vector, g Grad) {
 	t.In.Data.Propagate(transU, g)
 }
 
+func (t *transposeRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Transpose",
+		Inputs:    []interface{}{t.In.Data},
+		OutputLen: t.Out.Len(),
+	}
+}
+
 // A MatrixBatch is a batch of matrices, packed one after
 // another in a vector.
 type MatrixBatch struct {
@@ -194,7 +226,7 @@ func (m *MatrixBatch) anyvecMat() *anyvec.MatrixBatch {
 
 func (m *MatrixBatch) anyvecUpstream(g Grad) (mat *anyvec.MatrixBatch, isVar bool) {
 	mat = &anyvec.MatrixBatch{Rows: m.Rows, Cols: m.Cols, Num: m.Num}
-	if v, ok := m.Data.(*Var); ok {
+	if v, ok := m.Data.(*Var); ok && !hasRowGrad(g, v) {
 		if g[v] == nil {
 			panic("no gradient for variable")
 		}
@@ -240,14 +272,14 @@ func BatchedMatMul(trans1, trans2 bool, m1, m2 *MatrixBatch) *MatrixBatch {
 	anyM3.Product(trans1, trans2, c.MakeNumeric(1), anyM1, anyM2, c.MakeNumeric(0))
 
 	return &MatrixBatch{
-		Data: &batchedMatMulRes{
+		Data: trackRes(&batchedMatMulRes{
 			OutRes: anyM3.Data,
 			Deps:   MergeVarSets(m1.Data.Vars(), m2.Data.Vars()),
 			Trans1: trans1,
 			Trans2: trans2,
 			M1:     m1,
 			M2:     m2,
-		},
+		}),
 		Num:  m1.Num,
 		Rows: outRows,
 		Cols: outCols,
@@ -302,6 +334,14 @@ func (b *batchedMatMulRes) Propagate(u anyvec.Vector, g Grad) {
 	}
 }
 
+func (b *batchedMatMulRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "BatchedMatMul",
+		Inputs:    []interface{}{b.M1.Data, b.M2.Data},
+		OutputLen: b.OutRes.Len(),
+	}
+}
+
 func (b *batchedMatMulRes) upstreamMat(u anyvec.Vector) *anyvec.MatrixBatch {
 	outRows, outCols := b.M1.Rows, b.M2.Cols
 	if b.Trans1 {
@@ -34,12 +34,12 @@ func Pool(r Res, f func(r Res) Res) Res {
 	}
 	newVars := MergeVarSets(out.Vars(), r.Vars())
 	newVars.Del(pool)
-	return &poolRes{
+	return trackRes(&poolRes{
 		Pool: pool,
 		V:    newVars,
 		In:   r,
 		Out:  out,
-	}
+	})
 }
 
 func (p *poolRes) Output() anyvec.Vector {
@@ -63,6 +63,14 @@ func (p *poolRes) Propagate(u anyvec.Vector, g Grad) {
 	}
 }
 
+func (p *poolRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Pool",
+		Inputs:    []interface{}{p.In, p.Out},
+		OutputLen: p.Out.Output().Len(),
+	}
+}
+
 type poolMultiRes struct {
 	In    MultiRes
 	Out   MultiRes
@@ -86,12 +94,12 @@ func PoolMulti(m MultiRes, f func(reses []Res) MultiRes) MultiRes {
 	for _, x := range pool {
 		vars.Del(x)
 	}
-	return &poolMultiRes{
+	return trackMultiRes(&poolMultiRes{
 		In:    m,
 		Out:   out,
 		Pools: pool,
 		V:     vars,
-	}
+	})
 }
 
 func (p *poolMultiRes) Outputs() []anyvec.Vector {
@@ -122,6 +130,14 @@ func (p *poolMultiRes) Propagate(u []anyvec.Vector, g Grad) {
 	}
 }
 
+func (p *poolMultiRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "PoolMulti",
+		Inputs:    []interface{}{p.In, p.Out},
+		OutputLen: multiOutputLen(p.Out.Outputs()),
+	}
+}
+
 // PoolFork pools a single input to produce multiple
 // outputs.
 //
@@ -143,7 +159,7 @@ type noRepropRes struct {
 // result is back-propagated through more than once, it
 // panics.
 func NoReprop(r Res) Res {
-	return &noRepropRes{Res: r}
+	return trackRes(&noRepropRes{Res: r})
 }
 
 func (n *noRepropRes) Propagate(u anyvec.Vector, g Grad) {
@@ -156,3 +172,11 @@ func (n *noRepropRes) Propagate(u anyvec.Vector, g Grad) {
 	n.PropLock.Unlock()
 	n.Res.Propagate(u, g)
 }
+
+func (n *noRepropRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "NoReprop",
+		Inputs:    []interface{}{n.Res},
+		OutputLen: n.Output().Len(),
+	}
+}
@@ -36,7 +36,7 @@ func Fuse(reses ...Res) MultiRes {
 		res.Outs = append(res.Outs, x.Output())
 		res.V = MergeVarSets(res.V, x.Vars())
 	}
-	return res
+	return trackMultiRes(res)
 }
 
 func (f *fuseRes) Outputs() []anyvec.Vector {
@@ -53,6 +53,14 @@ func (f *fuseRes) Propagate(u []anyvec.Vector, g Grad) {
 	}
 }
 
+func (f *fuseRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Fuse",
+		Inputs:    resInputs(f.Ins),
+		OutputLen: multiOutputLen(f.Outs),
+	}
+}
+
 // FuseMulti fuses together the results of multiple
 // MultiRes instances.
 func FuseMulti(m ...MultiRes) MultiRes {
@@ -93,12 +101,12 @@ func Unfuse(m MultiRes, f func(reses []Res) Res) Res {
 	for _, x := range pool {
 		vars.Del(x)
 	}
-	return &unfuseRes{
+	return trackRes(&unfuseRes{
 		In:    m,
 		Out:   out,
 		Pools: pool,
 		V:     vars,
-	}
+	})
 }
 
 func (u *unfuseRes) Output() anyvec.Vector {
@@ -126,3 +134,11 @@ func (u *unfuseRes) Propagate(up anyvec.Vector, g Grad) {
 		u.In.Propagate(down, g)
 	}
 }
+
+func (u *unfuseRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Unfuse",
+		Inputs:    []interface{}{u.In, u.Out},
+		OutputLen: u.Out.Output().Len(),
+	}
+}
@@ -0,0 +1,229 @@
+package anydiff
+
+import "github.com/unixpickle/anyvec"
+
+type layerNormRes struct {
+	In        Res
+	ChunkSize int
+	OutVec    anyvec.Vector
+	InvStd    anyvec.Vector
+}
+
+// LayerNorm normalizes each chunk in a packed list of
+// chunks to have zero mean and unit variance.
+//
+// The epsilon argument is added to each variance for
+// numerical stability.
+// The chunk size must divide the vector length.
+// If chunkSize is 0, it will be treated like the full
+// length of v.
+//
+// To apply a learned gain and bias, use ScaleAddRepeated
+// on the result.
+func LayerNorm(v Res, chunkSize int, epsilon float64) Res {
+	chunkSize = checkChunkSize(v.Output(), chunkSize)
+	numChunks := v.Output().Len() / chunkSize
+	c := v.Output().Creator()
+
+	out := v.Output().Copy()
+	negMeans := anyvec.SumCols(out, numChunks)
+	negMeans.Scale(c.MakeNumeric(-1 / float64(chunkSize)))
+	anyvec.AddChunks(out, negMeans)
+
+	squares := out.Copy()
+	squares.Mul(out)
+	invStd := anyvec.SumCols(squares, numChunks)
+	invStd.Scale(c.MakeNumeric(1 / float64(chunkSize)))
+	invStd.AddScalar(c.MakeNumeric(epsilon))
+	anyvec.Pow(invStd, c.MakeNumeric(-0.5))
+	anyvec.ScaleChunks(out, invStd)
+
+	return trackRes(&layerNormRes{
+		In:        v,
+		ChunkSize: chunkSize,
+		OutVec:    out,
+		InvStd:    invStd,
+	})
+}
+
+func (l *layerNormRes) Output() anyvec.Vector {
+	return l.OutVec
+}
+
+func (l *layerNormRes) Vars() VarSet {
+	return l.In.Vars()
+}
+
+func (l *layerNormRes) Propagate(u anyvec.Vector, g Grad) {
+	numChunks := u.Len() / l.ChunkSize
+	scaler := u.Creator().MakeNumeric(-1 / float64(l.ChunkSize))
+
+	prod := u.Copy()
+	prod.Mul(l.OutVec)
+	negProdMeans := anyvec.SumCols(prod, numChunks)
+	negProdMeans.Scale(scaler)
+	negMeans := anyvec.SumCols(u, numChunks)
+	negMeans.Scale(scaler)
+
+	down := l.OutVec.Copy()
+	anyvec.ScaleChunks(down, negProdMeans)
+	down.Add(u)
+	anyvec.AddChunks(down, negMeans)
+	anyvec.ScaleChunks(down, l.InvStd)
+	l.In.Propagate(down, g)
+}
+
+func (l *layerNormRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "LayerNorm",
+		Inputs:    []interface{}{l.In},
+		OutputLen: l.OutVec.Len(),
+	}
+}
+
+// BatchNorm normalizes each column of a matrix, treating
+// the rows as a batch of samples.
+//
+// To apply a learned gain and bias, use ScaleAddRepeated
+// on the result.
+type BatchNorm struct {
+	// Epsilon is added to each variance for numerical
+	// stability.
+	Epsilon float64
+
+	// Momentum determines how quickly the running
+	// statistics change.
+	// After each training batch, every running statistic s
+	// becomes (1-Momentum)*s + Momentum*b, where b is the
+	// corresponding statistic for the batch.
+	Momentum float64
+
+	// RunningMean and RunningVar store the running mean and
+	// variance of each column.
+	RunningMean anyvec.Vector
+	RunningVar  anyvec.Vector
+
+	// Training indicates whether to normalize using the
+	// statistics of each batch and update the running
+	// statistics, or to normalize using the running
+	// statistics (i.e. inference mode).
+	Training bool
+}
+
+// NewBatchNorm creates a BatchNorm in training mode with
+// zero running means and unit running variances.
+func NewBatchNorm(c anyvec.Creator, cols int) *BatchNorm {
+	runningVar := c.MakeVector(cols)
+	runningVar.AddScalar(c.MakeNumeric(1))
+	return &BatchNorm{
+		Epsilon:     1e-5,
+		Momentum:    0.1,
+		RunningMean: c.MakeVector(cols),
+		RunningVar:  runningVar,
+		Training:    true,
+	}
+}
+
+// Apply normalizes the columns of the matrix.
+//
+// In training mode, the running statistics are updated
+// using the batch mean and (biased) batch variance.
+func (b *BatchNorm) Apply(m *Matrix) *Matrix {
+	if m.Cols != b.RunningMean.Len() {
+		panic("column count must match statistic count")
+	}
+	c := m.Data.Output().Creator()
+	if !b.Training {
+		negMean := b.RunningMean.Copy()
+		negMean.Scale(c.MakeNumeric(-1))
+		invStd := b.RunningVar.Copy()
+		invStd.AddScalar(c.MakeNumeric(b.Epsilon))
+		anyvec.Pow(invStd, c.MakeNumeric(-0.5))
+		centered := AddRepeated(m.Data, NewConst(negMean))
+		return &Matrix{
+			Data: ScaleRepeated(centered, NewConst(invStd)),
+			Rows: m.Rows,
+			Cols: m.Cols,
+		}
+	}
+
+	scaler := c.MakeNumeric(1 / float64(m.Rows))
+	mean := anyvec.SumRows(m.Data.Output(), m.Cols)
+	mean.Scale(scaler)
+
+	out := m.Data.Output().Copy()
+	negMean := mean.Copy()
+	negMean.Scale(c.MakeNumeric(-1))
+	anyvec.AddRepeated(out, negMean)
+
+	squares := out.Copy()
+	squares.Mul(out)
+	variance := anyvec.SumRows(squares, m.Cols)
+	variance.Scale(scaler)
+
+	invStd := variance.Copy()
+	invStd.AddScalar(c.MakeNumeric(b.Epsilon))
+	anyvec.Pow(invStd, c.MakeNumeric(-0.5))
+	anyvec.ScaleRepeated(out, invStd)
+
+	b.updateRunning(b.RunningMean, mean)
+	b.updateRunning(b.RunningVar, variance)
+
+	return &Matrix{
+		Data: trackRes(&batchNormRes{
+			In:     m,
+			OutVec: out,
+			InvStd: invStd,
+		}),
+		Rows: m.Rows,
+		Cols: m.Cols,
+	}
+}
+
+func (b *BatchNorm) updateRunning(running, batch anyvec.Vector) {
+	c := running.Creator()
+	running.Scale(c.MakeNumeric(1 - b.Momentum))
+	scaled := batch.Copy()
+	scaled.Scale(c.MakeNumeric(b.Momentum))
+	running.Add(scaled)
+}
+
+type batchNormRes struct {
+	In     *Matrix
+	OutVec anyvec.Vector
+	InvStd anyvec.Vector
+}
+
+func (b *batchNormRes) Output() anyvec.Vector {
+	return b.OutVec
+}
+
+func (b *batchNormRes) Vars() VarSet {
+	return b.In.Data.Vars()
+}
+
+func (b *batchNormRes) Propagate(u anyvec.Vector, g Grad) {
+	scaler := u.Creator().MakeNumeric(-1 / float64(b.In.Rows))
+
+	prod := u.Copy()
+	prod.Mul(b.OutVec)
+	negProdMeans := anyvec.SumRows(prod, b.In.Cols)
+	negProdMeans.Scale(scaler)
+	negMeans := anyvec.SumRows(u, b.In.Cols)
+	negMeans.Scale(scaler)
+
+	down := b.OutVec.Copy()
+	anyvec.ScaleRepeated(down, negProdMeans)
+	down.Add(u)
+	anyvec.AddRepeated(down, negMeans)
+	anyvec.ScaleRepeated(down, b.InvStd)
+	b.In.Data.Propagate(down, g)
+}
+
+func (b *batchNormRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "BatchNorm",
+		Inputs:    []interface{}{b.In.Data},
+		OutputLen: b.OutVec.Len(),
+	}
+}
@@ -0,0 +1,86 @@
+package anydiff
+
+import "github.com/unixpickle/anyvec"
+
+type opRes struct {
+	Name     string
+	Ins      []Res
+	OutVec   anyvec.Vector
+	V        VarSet
+	Backward func(u anyvec.Vector, down []anyvec.Vector)
+}
+
+// NewOp creates a differentiable operation from its output
+// and a function which computes its gradient.
+//
+// The name identifies the operation in graph dumps and
+// anomaly reports (see NodeInfo).
+// If it is empty, "Op" is used.
+//
+// The backward function is called during propagation with
+// an upstream vector and one downstream vector per input.
+// Each downstream vector is zeroed and has the same length
+// as its input's output, and backward should fill it in
+// with the gradient for that input.
+// The downstream vector is nil for any input which is not
+// needed.
+//
+// Inputs which do not depend on any of the Vars being
+// propagated through (e.g. constants) are never needed,
+// and backward is not called at all if no input is.
+//
+// Like the upstream vectors passed to Propagate, the
+// upstream vector passed to backward may be modified.
+func NewOp(name string, output anyvec.Vector, inputs []Res,
+	backward func(u anyvec.Vector, down []anyvec.Vector)) Res {
+	if name == "" {
+		name = "Op"
+	}
+	vars := VarSet{}
+	for _, x := range inputs {
+		vars = MergeVarSets(vars, x.Vars())
+	}
+	return trackRes(&opRes{
+		Name:     name,
+		Ins:      inputs,
+		OutVec:   output,
+		V:        vars,
+		Backward: backward,
+	})
+}
+
+func (o *opRes) Output() anyvec.Vector {
+	return o.OutVec
+}
+
+func (o *opRes) Vars() VarSet {
+	return o.V
+}
+
+func (o *opRes) Propagate(u anyvec.Vector, g Grad) {
+	down := make([]anyvec.Vector, len(o.Ins))
+	var anyNeeded bool
+	for i, x := range o.Ins {
+		if g.Intersects(x.Vars()) {
+			down[i] = u.Creator().MakeVector(x.Output().Len())
+			anyNeeded = true
+		}
+	}
+	if !anyNeeded {
+		return
+	}
+	o.Backward(u, down)
+	for i, x := range o.Ins {
+		if down[i] != nil {
+			x.Propagate(down[i], g)
+		}
+	}
+}
+
+func (o *opRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        o.Name,
+		Inputs:    resInputs(o.Ins),
+		OutputLen: o.OutVec.Len(),
+	}
+}
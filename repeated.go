@@ -20,12 +20,12 @@ func AddRepeated(v, biases Res) Res {
 	}
 	sum := v.Output().Copy()
 	anyvec.AddRepeated(sum, biases.Output())
-	return &addRepeatedRes{
+	return trackRes(&addRepeatedRes{
 		In:     v,
 		Bias:   biases,
 		V:      MergeVarSets(v.Vars(), biases.Vars()),
 		OutVec: sum,
-	}
+	})
 }
 
 func (a *addRepeatedRes) Output() anyvec.Vector {
@@ -47,6 +47,14 @@ func (a *addRepeatedRes) Propagate(u anyvec.Vector, g Grad) {
 	}
 }
 
+func (a *addRepeatedRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "AddRepeated",
+		Inputs:    []interface{}{a.In, a.Bias},
+		OutputLen: a.OutVec.Len(),
+	}
+}
+
 type scaleRepeatedRes struct {
 	In      Res
 	Scalers Res
@@ -65,12 +73,12 @@ func ScaleRepeated(v, scalers Res) Res {
 	}
 	sum := v.Output().Copy()
 	anyvec.ScaleRepeated(sum, scalers.Output())
-	return &scaleRepeatedRes{
+	return trackRes(&scaleRepeatedRes{
 		In:      v,
 		Scalers: scalers,
 		V:       MergeVarSets(v.Vars(), scalers.Vars()),
 		OutVec:  sum,
-	}
+	})
 }
 
 func (s *scaleRepeatedRes) Output() anyvec.Vector {
@@ -95,6 +103,14 @@ func (s *scaleRepeatedRes) Propagate(u anyvec.Vector, g Grad) {
 	}
 }
 
+func (s *scaleRepeatedRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "ScaleRepeated",
+		Inputs:    []interface{}{s.In, s.Scalers},
+		OutputLen: s.OutVec.Len(),
+	}
+}
+
 type scaleAddRepeatedRes struct {
 	In      Res
 	Scalers Res
@@ -121,13 +137,13 @@ func ScaleAddRepeated(v, scalers, biases Res) Res {
 	anyvec.ScaleRepeated(out, scalers.Output())
 	anyvec.AddRepeated(out, biases.Output())
 	vars := MergeVarSets(v.Vars(), scalers.Vars(), biases.Vars())
-	return &scaleAddRepeatedRes{
+	return trackRes(&scaleAddRepeatedRes{
 		In:      v,
 		Scalers: scalers,
 		Biases:  biases,
 		OutVec:  out,
 		V:       vars,
-	}
+	})
 }
 
 func (s *scaleAddRepeatedRes) Output() anyvec.Vector {
@@ -156,3 +172,11 @@ func (s *scaleAddRepeatedRes) Propagate(u anyvec.Vector, g Grad) {
 		s.In.Propagate(u, g)
 	}
 }
+
+func (s *scaleAddRepeatedRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "ScaleAddRepeated",
+		Inputs:    []interface{}{s.In, s.Scalers, s.Biases},
+		OutputLen: s.OutVec.Len(),
+	}
+}
@@ -0,0 +1,306 @@
+package anydiff
+
+import "github.com/unixpickle/anyvec"
+
+// A RowGrad is a sparse gradient for a variable which is
+// treated as a matrix, such as an embedding table.
+// It only stores the rows which have been touched.
+//
+// A RowGrad implements anyvec.Vector, so it can be stored
+// in a Grad in place of a dense vector.
+// Operations which cannot preserve sparsity (e.g.
+// AddScalar, or Add with a dense vector) make the RowGrad
+// store every row.
+//
+// Slice and Data produce copies of the dense gradient.
+// Unlike with a normal vector, modifying the result of
+// Slice does not modify the RowGrad.
+// Package-level anyvec functions (e.g. anyvec.Pow) do not
+// support a RowGrad; use Dense or Values instead.
+type RowGrad struct {
+	NumRows int
+	RowSize int
+
+	// Indices stores the unique indices of the touched
+	// rows.
+	Indices []int
+
+	// Values stores the touched rows, packed one after
+	// another in the same order as Indices.
+	Values anyvec.Vector
+
+	mapper anyvec.Mapper
+}
+
+// NewRowGrad creates an empty RowGrad for the variable,
+// treating the variable as a matrix with rows of the given
+// size.
+//
+// To request a sparse gradient from back-propagation,
+// store the RowGrad in the Grad in place of the variable's
+// dense gradient.
+func NewRowGrad(v *Var, rowSize int) *RowGrad {
+	if rowSize <= 0 || v.Vector.Len()%rowSize != 0 {
+		panic("row size must divide variable size")
+	}
+	return &RowGrad{
+		NumRows: v.Vector.Len() / rowSize,
+		RowSize: rowSize,
+		Values:  v.Vector.Creator().MakeVector(0),
+	}
+}
+
+// Reset removes every row from the gradient, so that it
+// is zero and no longer touches any rows.
+func (r *RowGrad) Reset() {
+	r.Indices = nil
+	r.Values = r.Creator().MakeVector(0)
+	r.mapper = nil
+}
+
+// AddRows adds packed rows to the gradient.
+//
+// The ids specify the row index for each packed row.
+// They may contain duplicates, in which case the rows are
+// summed.
+func (r *RowGrad) AddRows(ids []int, rows anyvec.Vector) {
+	if rows.Len() != len(ids)*r.RowSize {
+		panic("row count mismatch")
+	}
+	positions := map[int]int{}
+	indices := append([]int{}, r.Indices...)
+	for i, idx := range indices {
+		positions[idx] = i
+	}
+	table := make([]int, 0, (len(r.Indices)+len(ids))*r.RowSize)
+	for i := range r.Indices {
+		for j := 0; j < r.RowSize; j++ {
+			table = append(table, i*r.RowSize+j)
+		}
+	}
+	for _, id := range ids {
+		if id < 0 || id >= r.NumRows {
+			panic("row index out of range")
+		}
+		pos, ok := positions[id]
+		if !ok {
+			pos = len(indices)
+			positions[id] = pos
+			indices = append(indices, id)
+		}
+		for j := 0; j < r.RowSize; j++ {
+			table = append(table, pos*r.RowSize+j)
+		}
+	}
+	if len(table) == 0 {
+		return
+	}
+	c := r.Creator()
+	merged := c.MakeVector(len(indices) * r.RowSize)
+	mapper := c.MakeMapper(merged.Len(), table)
+	mapper.MapTranspose(c.Concat(r.Values, rows), merged)
+	r.Indices = indices
+	r.Values = merged
+	r.mapper = nil
+}
+
+// Gather extracts the rows of a dense vector which are
+// present in the gradient, packing them in the same order
+// as r.Values.
+func (r *RowGrad) Gather(v anyvec.Vector) anyvec.Vector {
+	if v.Len() != r.Len() {
+		panic("length mismatch")
+	}
+	res := v.Creator().MakeVector(len(r.Indices) * r.RowSize)
+	if res.Len() > 0 {
+		r.rowMapper().Map(v, res)
+	}
+	return res
+}
+
+// Scatter adds packed rows, ordered like r.Values, to the
+// corresponding rows of a dense vector.
+func (r *RowGrad) Scatter(rows, v anyvec.Vector) {
+	if v.Len() != r.Len() {
+		panic("length mismatch")
+	}
+	if rows.Len() != len(r.Indices)*r.RowSize {
+		panic("row count mismatch")
+	}
+	if rows.Len() > 0 {
+		r.rowMapper().MapTranspose(rows, v)
+	}
+}
+
+// Dense creates a dense version of the gradient.
+func (r *RowGrad) Dense() anyvec.Vector {
+	res := r.Creator().MakeVector(r.Len())
+	r.Scatter(r.Values, res)
+	return res
+}
+
+// Creator returns the creator of r.Values.
+func (r *RowGrad) Creator() anyvec.Creator {
+	return r.Values.Creator()
+}
+
+// Len returns the length of the dense gradient.
+func (r *RowGrad) Len() int {
+	return r.NumRows * r.RowSize
+}
+
+// Overlaps checks if the vector overlaps with r.Values.
+func (r *RowGrad) Overlaps(v anyvec.Vector) bool {
+	if rg, ok := v.(*RowGrad); ok {
+		return rg == r || r.Values.Overlaps(rg.Values)
+	}
+	return r.Values.Overlaps(v)
+}
+
+// Data returns the data of the dense gradient.
+func (r *RowGrad) Data() anyvec.NumericList {
+	return r.Dense().Data()
+}
+
+// SetData sets the dense gradient.
+func (r *RowGrad) SetData(d anyvec.NumericList) {
+	vec := r.Creator().MakeVector(r.Len())
+	vec.SetData(d)
+	r.setDense(vec)
+}
+
+// Set copies the contents of v into r.
+func (r *RowGrad) Set(v anyvec.Vector) {
+	if rg, ok := v.(*RowGrad); ok {
+		if rg != r {
+			r.assertSameShape(rg)
+			r.Indices = append([]int{}, rg.Indices...)
+			r.Values = rg.Values.Copy()
+			r.mapper = nil
+		}
+	} else {
+		r.setDense(v.Copy())
+	}
+}
+
+// Copy creates a copy of the sparse gradient.
+func (r *RowGrad) Copy() anyvec.Vector {
+	return &RowGrad{
+		NumRows: r.NumRows,
+		RowSize: r.RowSize,
+		Indices: append([]int{}, r.Indices...),
+		Values:  r.Values.Copy(),
+		mapper:  r.mapper,
+	}
+}
+
+// Slice copies a range of the dense gradient.
+func (r *RowGrad) Slice(start, end int) anyvec.Vector {
+	return r.Dense().Slice(start, end)
+}
+
+// Scale scales the gradient.
+func (r *RowGrad) Scale(s anyvec.Numeric) {
+	r.Values.Scale(s)
+}
+
+// AddScalar adds a scalar to every component of the
+// dense gradient.
+func (r *RowGrad) AddScalar(s anyvec.Numeric) {
+	vec := r.Dense()
+	vec.AddScalar(s)
+	r.setDense(vec)
+}
+
+// Dot computes the dot product of the dense gradient with
+// another vector.
+func (r *RowGrad) Dot(v anyvec.Vector) anyvec.Numeric {
+	if v == anyvec.Vector(r) {
+		return r.Values.Dot(r.Values)
+	}
+	return r.Values.Dot(r.Gather(denseVector(v)))
+}
+
+// Add adds v to the gradient.
+//
+// If v is a *RowGrad, the result is the union of the rows
+// in both gradients.
+func (r *RowGrad) Add(v anyvec.Vector) {
+	if rg, ok := v.(*RowGrad); ok {
+		r.assertSameShape(rg)
+		if rg == r {
+			r.Values.Scale(r.Creator().MakeNumeric(2))
+		} else {
+			r.AddRows(rg.Indices, rg.Values)
+		}
+	} else {
+		vec := r.Dense()
+		vec.Add(v)
+		r.setDense(vec)
+	}
+}
+
+// Sub subtracts v from the gradient.
+func (r *RowGrad) Sub(v anyvec.Vector) {
+	neg := v.Copy()
+	neg.Scale(r.Creator().MakeNumeric(-1))
+	r.Add(neg)
+}
+
+// Mul multiplies the gradient component-wise by v.
+// Rows which are not present remain zero.
+func (r *RowGrad) Mul(v anyvec.Vector) {
+	r.Values.Mul(r.Gather(denseVector(v)))
+}
+
+// Div divides the gradient component-wise by v.
+// Rows which are not present remain zero.
+func (r *RowGrad) Div(v anyvec.Vector) {
+	r.Values.Div(r.Gather(denseVector(v)))
+}
+
+func (r *RowGrad) setDense(v anyvec.Vector) {
+	if v.Len() != r.Len() {
+		panic("length mismatch")
+	}
+	r.Indices = make([]int, r.NumRows)
+	for i := range r.Indices {
+		r.Indices[i] = i
+	}
+	r.Values = v
+	r.mapper = nil
+}
+
+func (r *RowGrad) rowMapper() anyvec.Mapper {
+	if r.mapper == nil {
+		table := make([]int, 0, len(r.Indices)*r.RowSize)
+		for _, idx := range r.Indices {
+			for j := 0; j < r.RowSize; j++ {
+				table = append(table, idx*r.RowSize+j)
+			}
+		}
+		r.mapper = r.Creator().MakeMapper(r.Len(), table)
+	}
+	return r.mapper
+}
+
+func (r *RowGrad) assertSameShape(r1 *RowGrad) {
+	if r.NumRows != r1.NumRows || r.RowSize != r1.RowSize {
+		panic("row gradient shape mismatch")
+	}
+}
+
+func denseVector(v anyvec.Vector) anyvec.Vector {
+	if rg, ok := v.(*RowGrad); ok {
+		return rg.Dense()
+	}
+	return v
+}
+
+// hasRowGrad checks if the gradient for v is sparse, in
+// which case it cannot be sliced or updated in place like
+// a dense gradient.
+func hasRowGrad(g Grad, v *Var) bool {
+	_, ok := g[v].(*RowGrad)
+	return ok
+}
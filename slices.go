@@ -18,12 +18,12 @@ func Slice(in Res, start, end int) Res {
 	if start < 0 || start > end || end > in.Output().Len() {
 		panic("index out of range")
 	}
-	return &sliceRes{
+	return trackRes(&sliceRes{
 		In:     in,
 		OutVec: in.Output().Slice(start, end),
 		Start:  start,
 		End:    end,
-	}
+	})
 }
 
 func (s *sliceRes) Output() anyvec.Vector {
@@ -35,7 +35,7 @@ func (s *sliceRes) Vars() VarSet {
 }
 
 func (s *sliceRes) Propagate(u anyvec.Vector, g Grad) {
-	if v, ok := s.In.(*Var); ok {
+	if v, ok := s.In.(*Var); ok && !hasRowGrad(g, v) {
 		if uVec, ok := g[v]; ok {
 			uVec.Slice(s.Start, s.End).Add(u)
 		}
@@ -47,6 +47,14 @@ func (s *sliceRes) Propagate(u anyvec.Vector, g Grad) {
 	}
 }
 
+func (s *sliceRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Slice",
+		Inputs:    []interface{}{s.In},
+		OutputLen: s.OutVec.Len(),
+	}
+}
+
 type concatRes struct {
 	Ins    []Res
 	OutVec anyvec.Vector
@@ -64,11 +72,11 @@ func Concat(ins ...Res) Res {
 		vecs[i] = x.Output()
 		vars = MergeVarSets(vars, x.Vars())
 	}
-	return &concatRes{
+	return trackRes(&concatRes{
 		Ins:    ins,
 		OutVec: ins[0].Output().Creator().Concat(vecs...),
 		V:      vars,
-	}
+	})
 }
 
 func (c *concatRes) Output() anyvec.Vector {
@@ -90,6 +98,14 @@ func (c *concatRes) Propagate(u anyvec.Vector, g Grad) {
 	}
 }
 
+func (c *concatRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Concat",
+		Inputs:    resInputs(c.Ins),
+		OutputLen: c.OutVec.Len(),
+	}
+}
+
 // Split splits the vector into n evenly-sized pieces.
 //
 // The vector's length must be divisible by n.
@@ -88,6 +88,13 @@ func (v *Var) Propagate(upstream anyvec.Vector, g Grad) {
 	}
 }
 
+func (v *Var) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Var",
+		OutputLen: v.Vector.Len(),
+	}
+}
+
 // A Const is similar to a Var, but it does not report
 // itself as depending on any Vars.
 // Thus, Consts are good for pieces of data you will never
@@ -114,3 +121,10 @@ func (c *Const) Vars() VarSet {
 // Propagate does nothing, since c is a constant.
 func (c *Const) Propagate(upstream anyvec.Vector, g Grad) {
 }
+
+func (c *Const) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Const",
+		OutputLen: c.Vector.Len(),
+	}
+}
@@ -12,11 +12,11 @@ type scaleRes struct {
 func Scale(v Res, s anyvec.Numeric) Res {
 	newData := v.Output().Copy()
 	newData.Scale(s)
-	return &scaleRes{
+	return trackRes(&scaleRes{
 		In:     v,
 		OutRes: newData,
 		Scaler: s,
-	}
+	})
 }
 
 func (s *scaleRes) Output() anyvec.Vector {
@@ -32,6 +32,14 @@ func (s *scaleRes) Propagate(u anyvec.Vector, g Grad) {
 	s.In.Propagate(u, g)
 }
 
+func (s *scaleRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Scale",
+		Inputs:    []interface{}{s.In},
+		OutputLen: s.OutRes.Len(),
+	}
+}
+
 type addScalerRes struct {
 	In     Res
 	OutVec anyvec.Vector
@@ -41,10 +49,10 @@ type addScalerRes struct {
 func AddScalar(v Res, s anyvec.Numeric) Res {
 	newData := v.Output().Copy()
 	newData.AddScalar(s)
-	return &addScalerRes{
+	return trackRes(&addScalerRes{
 		In:     v,
 		OutVec: newData,
-	}
+	})
 }
 
 func (a *addScalerRes) Output() anyvec.Vector {
@@ -59,6 +67,14 @@ func (a *addScalerRes) Propagate(u anyvec.Vector, g Grad) {
 	a.In.Propagate(u, g)
 }
 
+func (a *addScalerRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "AddScalar",
+		Inputs:    []interface{}{a.In},
+		OutputLen: a.OutVec.Len(),
+	}
+}
+
 type addRes struct {
 	In1    Res
 	In2    Res
@@ -73,12 +89,12 @@ func Add(v1, v2 Res) Res {
 	}
 	newData := v1.Output().Copy()
 	newData.Add(v2.Output())
-	return &addRes{
+	return trackRes(&addRes{
 		In1:    v1,
 		In2:    v2,
 		V:      MergeVarSets(v1.Vars(), v2.Vars()),
 		OutRes: newData,
-	}
+	})
 }
 
 func (a *addRes) Output() anyvec.Vector {
@@ -102,6 +118,14 @@ func (a *addRes) Propagate(u anyvec.Vector, g Grad) {
 	}
 }
 
+func (a *addRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Add",
+		Inputs:    []interface{}{a.In1, a.In2},
+		OutputLen: a.OutRes.Len(),
+	}
+}
+
 type subRes struct {
 	In1    Res
 	In2    Res
@@ -116,12 +140,12 @@ func Sub(v1, v2 Res) Res {
 	}
 	newData := v1.Output().Copy()
 	newData.Sub(v2.Output())
-	return &subRes{
+	return trackRes(&subRes{
 		In1:    v1,
 		In2:    v2,
 		V:      MergeVarSets(v1.Vars(), v2.Vars()),
 		OutRes: newData,
-	}
+	})
 }
 
 func (a *subRes) Output() anyvec.Vector {
@@ -147,6 +171,14 @@ func (a *subRes) Propagate(u anyvec.Vector, g Grad) {
 	}
 }
 
+func (a *subRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Sub",
+		Inputs:    []interface{}{a.In1, a.In2},
+		OutputLen: a.OutRes.Len(),
+	}
+}
+
 type mulRes struct {
 	In1    Res
 	In2    Res
@@ -161,12 +193,12 @@ func Mul(v1, v2 Res) Res {
 	}
 	out := v1.Output().Copy()
 	out.Mul(v2.Output())
-	return &mulRes{
+	return trackRes(&mulRes{
 		In1:    v1,
 		In2:    v2,
 		V:      MergeVarSets(v1.Vars(), v2.Vars()),
 		OutVec: out,
-	}
+	})
 }
 
 func (m *mulRes) Output() anyvec.Vector {
@@ -195,6 +227,14 @@ func (m *mulRes) Propagate(u anyvec.Vector, g Grad) {
 	}
 }
 
+func (m *mulRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Mul",
+		Inputs:    []interface{}{m.In1, m.In2},
+		OutputLen: m.OutVec.Len(),
+	}
+}
+
 type divRes struct {
 	In1    Res
 	In2    Res
@@ -209,12 +249,12 @@ func Div(num, denom Res) Res {
 	}
 	res := num.Output().Copy()
 	res.Div(denom.Output())
-	return &divRes{
+	return trackRes(&divRes{
 		In1:    num,
 		In2:    denom,
 		V:      MergeVarSets(num.Vars(), denom.Vars()),
 		OutVec: res,
-	}
+	})
 }
 
 func (d *divRes) Output() anyvec.Vector {
@@ -250,6 +290,14 @@ func (d *divRes) Propagate(u anyvec.Vector, g Grad) {
 	}
 }
 
+func (d *divRes) NodeInfo() *NodeInfo {
+	return &NodeInfo{
+		Op:        "Div",
+		Inputs:    []interface{}{d.In1, d.In2},
+		OutputLen: d.OutVec.Len(),
+	}
+}
+
 // Sum computes the complete sum of all the elements.
 func Sum(r Res) Res {
 	return SumCols(&Matrix{
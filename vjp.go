@@ -0,0 +1,20 @@
+package anydiff
+
+import "github.com/unixpickle/anyvec"
+
+// VJP computes the product of an upstream vector with the
+// Jacobian of r with respect to the given variables.
+//
+// The result maps each variable to the corresponding part
+// of the product.
+// The upstream vector is not modified.
+func VJP(r Res, upstream anyvec.Vector, vars ...*Var) Grad {
+	if upstream.Len() != r.Output().Len() {
+		panic("upstream size mismatch")
+	}
+	g := NewGrad(vars...)
+	if g.Intersects(r.Vars()) {
+		r.Propagate(upstream.Copy(), g)
+	}
+	return g
+}